@@ -0,0 +1,249 @@
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// diffKind describes how a value differs between the golden file and the actual result.
+type diffKind string
+
+const (
+	// diffAdded means the value exists in the actual result, but not in the golden file.
+	diffAdded diffKind = "added"
+	// diffRemoved means the value exists in the golden file, but not in the actual result.
+	diffRemoved diffKind = "removed"
+	// diffChanged means the value exists in both, has the same JSON type, but is different.
+	diffChanged diffKind = "changed"
+	// diffTypeChanged means the value exists in both, but has different JSON types.
+	diffTypeChanged diffKind = "type changed"
+)
+
+// maxDiffValueLen is the maximum length of a value in a diff report. Longer values are truncated.
+const maxDiffValueLen = 120
+
+// jsonDiff is a single difference between the golden file and the actual result.
+type jsonDiff struct {
+	kind diffKind
+	// path is the GJSON path to the value, or the XPath-like path for XML golden files.
+	path string
+	// old is the value in the golden file. It is nil when the kind is diffAdded.
+	old any
+	// new is the value in the actual result. It is nil when the kind is diffRemoved.
+	new any
+}
+
+// String returns a one-line human-readable representation of the difference.
+func (d jsonDiff) String() string {
+	switch d.kind {
+	case diffAdded:
+		return fmt.Sprintf("%-12s %s: %s", d.kind, d.path, formatDiffValue(d.new))
+	case diffRemoved:
+		return fmt.Sprintf("%-12s %s: %s", d.kind, d.path, formatDiffValue(d.old))
+	case diffTypeChanged:
+		return fmt.Sprintf("%-12s %s: %s (%s) => %s (%s)", d.kind, d.path,
+			formatDiffValue(d.old), jsonTypeName(d.old), formatDiffValue(d.new), jsonTypeName(d.new))
+	default:
+		return fmt.Sprintf("%-12s %s: %s => %s", d.kind, d.path, formatDiffValue(d.old), formatDiffValue(d.new))
+	}
+}
+
+// parseJSON parses a JSON or JSONC document into a generic value. Comments are stripped before parsing, and numbers
+// are kept as json.Number to retain their textual representation.
+func parseJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(stripJSONComments(data)))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after top-level value")
+	}
+	return v, nil
+}
+
+// diffJSON returns the differences between the golden (want) and the actual (got) values, which are expected to be
+// parsed by parseJSON. The differences are sorted in document order, with object keys visited alphabetically.
+func diffJSON(want, got any) []jsonDiff {
+	var diffs []jsonDiff
+	walkDiff("", want, got, &diffs)
+	return diffs
+}
+
+func walkDiff(path string, want, got any, diffs *[]jsonDiff) {
+	if jsonTypeName(want) != jsonTypeName(got) {
+		*diffs = append(*diffs, jsonDiff{kind: diffTypeChanged, path: rootPath(path), old: want, new: got})
+		return
+	}
+
+	switch w := want.(type) {
+	case map[string]any:
+		g := got.(map[string]any)
+		keys := make([]string, 0, len(w)+len(g))
+		for k := range w {
+			keys = append(keys, k)
+		}
+		for k := range g {
+			if _, ok := w[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			childPath := joinPath(path, escapePathKey(k))
+			wv, inWant := w[k]
+			gv, inGot := g[k]
+			switch {
+			case !inGot:
+				*diffs = append(*diffs, jsonDiff{kind: diffRemoved, path: childPath, old: wv})
+			case !inWant:
+				*diffs = append(*diffs, jsonDiff{kind: diffAdded, path: childPath, new: gv})
+			default:
+				walkDiff(childPath, wv, gv, diffs)
+			}
+		}
+	case []any:
+		g := got.([]any)
+		for i := 0; i < len(w) || i < len(g); i++ {
+			childPath := joinPath(path, strconv.Itoa(i))
+			switch {
+			case i >= len(g):
+				*diffs = append(*diffs, jsonDiff{kind: diffRemoved, path: childPath, old: w[i]})
+			case i >= len(w):
+				*diffs = append(*diffs, jsonDiff{kind: diffAdded, path: childPath, new: g[i]})
+			default:
+				walkDiff(childPath, w[i], g[i], diffs)
+			}
+		}
+	case json.Number:
+		if !equalNumbers(w, got.(json.Number)) {
+			*diffs = append(*diffs, jsonDiff{kind: diffChanged, path: rootPath(path), old: want, new: got})
+		}
+	default:
+		if want != got {
+			*diffs = append(*diffs, jsonDiff{kind: diffChanged, path: rootPath(path), old: want, new: got})
+		}
+	}
+}
+
+// equalNumbers reports whether a and b represent the same number, e.g. 1 and 1.0 are equal. Integers are compared
+// exactly, since those above 2^53 lose precision as floats, and other numbers are compared as floats.
+func equalNumbers(a, b json.Number) bool {
+	if a == b {
+		return true
+	}
+	ar, aOK := new(big.Rat).SetString(string(a))
+	br, bOK := new(big.Rat).SetString(string(b))
+	if aOK && bOK && ar.IsInt() && br.IsInt() {
+		return ar.Cmp(br) == 0
+	}
+	af, aErr := a.Float64()
+	bf, bErr := b.Float64()
+	return aErr == nil && bErr == nil && af == bf
+}
+
+// jsonTypeName returns the name of the JSON type of a value parsed by parseJSON.
+func jsonTypeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number, float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// formatDiffValue returns the compact JSON representation of a value, truncated to maxDiffValueLen.
+func formatDiffValue(v any) string {
+	// HTML characters are not escaped, since they are common in values such as XML elements.
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	s := strings.TrimSuffix(buf.String(), "\n")
+	if len(s) > maxDiffValueLen {
+		s = s[:maxDiffValueLen] + "..."
+	}
+	return s
+}
+
+// formatDiffs formats the differences as a report, one difference per line.
+func formatDiffs(diffs []jsonDiff) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d difference(s) between golden file (old) and actual result (new):", len(diffs))
+	for _, d := range diffs {
+		sb.WriteString("\n    ")
+		sb.WriteString(d.String())
+	}
+	return sb.String()
+}
+
+// joinPath appends a GJSON path component to a path.
+func joinPath(path, component string) string {
+	if path == "" {
+		return component
+	}
+	return path + "." + component
+}
+
+// rootPath returns "@this" for the root path, which is the GJSON path for the whole document, or the path otherwise.
+func rootPath(path string) string {
+	if path == "" {
+		return "@this"
+	}
+	return path
+}
+
+// escapePathKey escapes an object key so that it can be used as a component in a GJSON path.
+func escapePathKey(key string) string {
+	var sb strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if !isSafePathKeyChar(c) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// isSafePathKeyChar reports whether the character can be used in a GJSON path component without escaping.
+func isSafePathKeyChar(c byte) bool {
+	return c <= ' ' || c > '~' || c == '_' || c == '-' || c == ':' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9')
+}
+
+// diffGolden parses the golden file and the actual result and returns their structural differences. The returned
+// bool is false when either of them cannot be parsed, in which case only a text comparison is possible.
+func diffGolden(golden, actual []byte) ([]jsonDiff, bool) {
+	want, err := parseJSON(golden)
+	if err != nil {
+		return nil, false
+	}
+	got, err := parseJSON(actual)
+	if err != nil {
+		return nil, false
+	}
+	return diffJSON(want, got), true
+}
@@ -0,0 +1,121 @@
+package golden
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffGolden(t *testing.T) {
+	type args struct {
+		golden string
+		actual string
+	}
+	type given struct {
+		args args
+	}
+	type want struct {
+		// diffs are the string representations of the expected differences
+		diffs []string
+		// parsed is false when either document cannot be parsed
+		parsed bool
+	}
+	type test struct {
+		name  string
+		given given
+		want  want
+	}
+	tests := []test{
+		{
+			name: "no differences when documents only differ in formatting and comments",
+			given: given{
+				args: args{
+					golden: "/*\nfile comment\n*/\n\n{\n    \"age\": 30, // field comment\n    \"name\": \"John\"\n}",
+					actual: `{"name":"John","age":30.0}`,
+				},
+			},
+			want: want{parsed: true},
+		},
+		{
+			name: "reports added, removed, changed and type changed values",
+			given: given{
+				args: args{
+					golden: `{"age": 30, "name": "John", "colour": {"hair": "black"}, "tags": ["a", "b"]}`,
+					actual: `{"age": "30", "name": "Jane", "colour": {"hair": "black", "eyes": "brown"}, "tags": ["a"]}`,
+				},
+			},
+			want: want{
+				parsed: true,
+				diffs: []string{
+					`type changed age: 30 (number) => "30" (string)`,
+					`added        colour.eyes: "brown"`,
+					`changed      name: "John" => "Jane"`,
+					`removed      tags.1: "b"`,
+				},
+			},
+		},
+		{
+			name: "reports changed integers that are equal as floats",
+			given: given{
+				args: args{
+					golden: `{"id": 9007199254740993, "count": 1e2}`,
+					actual: `{"id": 9007199254740992, "count": 100}`,
+				},
+			},
+			want: want{
+				parsed: true,
+				diffs:  []string{`changed      id: 9007199254740993 => 9007199254740992`},
+			},
+		},
+		{
+			name: "escapes special characters in object keys",
+			given: given{
+				args: args{
+					golden: `{"fav.movie": "Deer Hunter"}`,
+					actual: `{"fav.movie": "Heat"}`,
+				},
+			},
+			want: want{
+				parsed: true,
+				diffs:  []string{`changed      fav\.movie: "Deer Hunter" => "Heat"`},
+			},
+		},
+		{
+			name: "reports the root path when the top-level type changes",
+			given: given{
+				args: args{
+					golden: `{}`,
+					actual: `[]`,
+				},
+			},
+			want: want{
+				parsed: true,
+				diffs:  []string{`type changed @this: {} (object) => [] (array)`},
+			},
+		},
+		{
+			name: "not parsed when the golden file is not valid JSON",
+			given: given{
+				args: args{
+					golden: `{"name": `,
+					actual: `{"name": "John"}`,
+				},
+			},
+			want: want{parsed: false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- When ---------------------------------- */
+			diffs, parsed := diffGolden([]byte(tt.given.args.golden), []byte(tt.given.args.actual))
+
+			/* ---------------------------------- Then ---------------------------------- */
+			require.Equal(t, tt.want.parsed, parsed)
+			var got []string
+			for _, d := range diffs {
+				got = append(got, d.String())
+			}
+			require.Equal(t, tt.want.diffs, got)
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package golden
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	gjsonpkg "github.com/tobbstr/golden/gjson"
+)
+
+// Document is a model of the golden file. It holds the JSON document that is compared with the golden file, and is
+// passed to every Option, which may check or modify it.
+//
+// For YAML golden files, the document is the JSON representation of the YAML document, which is converted to YAML
+// once all options are applied. For XML golden files, WithSkippedFields, WithStablePlaceholders, WithFieldComments and
+// WithFileComment work on the XML document instead, and their paths are XPath-like paths. See AssertXML.
+//
+// The paths accepted by its methods are GJSON paths.
+// See https://github.com/tidwall/gjson/blob/master/SYNTAX.md
+type Document struct {
+	result []byte
+	// writeActual is true when the actual result should be written to a ".actual" file if the comparison fails.
+	writeActual bool
+	// actualDir is the directory the ".actual" files are written to. If empty, they are written next to the golden
+	// file.
+	actualDir string
+	// createMissing is true when a missing golden file should be created from the actual result.
+	createMissing bool
+	// semantic is true when the golden file and the actual result are compared by their parsed values, ignoring
+	// formatting, key order and comments.
+	semantic bool
+	// normalizeLineEndings is true when "\r\n" and "\r" line endings are replaced with "\n" in both the golden file and
+	// the actual result before they are compared.
+	normalizeLineEndings bool
+	// update is true when the golden file should be updated with the rendered result, once all options are applied.
+	update bool
+	// placeholders maps the values replaced by WithStablePlaceholders to their placeholders, so that equal values get
+	// the same placeholder across options.
+	placeholders map[string]string
+	// xml is the XML document of XML golden files. It is nil for other formats.
+	xml *xmlNode
+	// t is the test the document is compared in. It is passed on to options adapted with FromTestingOption.
+	t testing.TB
+	// failNow is true when the test stops execution on failure. It is passed on to options adapted with
+	// FromTestingOption.
+	failNow bool
+}
+
+// NewDocument returns a Document holding the JSON data. It is useful for testing custom options.
+func NewDocument(data []byte) *Document {
+	return &Document{result: data}
+}
+
+// Bytes returns the JSON document.
+func (d *Document) Bytes() []byte {
+	return d.result
+}
+
+// SetBytes replaces the JSON document.
+func (d *Document) SetBytes(data []byte) {
+	d.result = data
+}
+
+// Get returns the value at the path. Use the Exists method of the returned value to check whether the path exists.
+func (d *Document) Get(path string) gjson.Result {
+	return gjson.GetBytes(d.result, path)
+}
+
+// ExpandPath expands a path with wildcards and queries, e.g. "users.#.age", into the concrete paths of the values
+// it matches, e.g. "users.0.age" and "users.1.age". The concrete paths can be passed to Set, SetRaw and Delete.
+func (d *Document) ExpandPath(path string) []string {
+	return gjsonpkg.ExpandPath(d.result, path)
+}
+
+// Set sets the value at the concrete path, i.e. a path without wildcards or queries. The value is marshalled to JSON.
+func (d *Document) Set(path string, value any) error {
+	res, err := sjson.SetBytes(d.result, path, value)
+	if err != nil {
+		return err
+	}
+	d.result = res
+	return nil
+}
+
+// SetRaw sets the raw JSON value at the concrete path, i.e. a path without wildcards or queries.
+func (d *Document) SetRaw(path string, raw []byte) error {
+	res, err := sjson.SetRawBytes(d.result, path, raw)
+	if err != nil {
+		return err
+	}
+	d.result = res
+	return nil
+}
+
+// Delete deletes the value at the concrete path, i.e. a path without wildcards or queries.
+func (d *Document) Delete(path string) error {
+	res, err := sjson.DeleteBytes(d.result, path)
+	if err != nil {
+		return err
+	}
+	d.result = res
+	return nil
+}
+
+// Fail marks the test as failed. If failNow is true, it also stops execution. It is meant to be used by a TestingOption
+// to report failures, passing on the failNow argument of TestingOption.Apply.
+//
+// Example: Fail(t, failNow, "path not found", "path = %s", path)
+func Fail(t testing.TB, failNow bool, failureMessage string, msgAndArgs ...any) {
+	t.Helper()
+	if failNow {
+		require.Fail(t, failureMessage, msgAndArgs...)
+		return
+	}
+	assert.Fail(t, failureMessage, msgAndArgs...)
+}
+
+// NoError marks the test as failed if err is not nil, and returns whether it was nil. If failNow is true, it also
+// stops execution. It is meant to be used by a TestingOption to report errors, passing on the failNow argument of
+// TestingOption.Apply.
+//
+// Example:
+//
+//	if !NoError(t, failNow, doc.Set(path, "masked"), "setting field value for path = %s", path) {
+//	    return
+//	}
+func NoError(t testing.TB, failNow bool, err error, msgAndArgs ...any) bool {
+	t.Helper()
+	if failNow {
+		require.NoError(t, err, msgAndArgs...)
+		return true
+	}
+	return assert.NoError(t, err, msgAndArgs...)
+}
@@ -0,0 +1,357 @@
+package golden
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// maskIDsOption is a custom option, implemented with the exported API only, that masks the values of the fields
+// matching a path if they look like IDs.
+type maskIDsOption struct {
+	path string
+}
+
+func (m maskIDsOption) Apply(doc *Document, _ string) error {
+	var errs []error
+	for _, path := range doc.ExpandPath(m.path) {
+		if !strings.HasPrefix(doc.Get(path).String(), "id-") {
+			errs = append(errs, &OptionError{Path: path, Reason: "not an ID"})
+			continue
+		}
+		if err := doc.Set(path, "--* MASKED *--"); err != nil {
+			errs = append(errs, &OptionError{Path: path, Reason: "masking field value", Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m maskIDsOption) IsType() OptionType {
+	return OptionTypeModifier
+}
+
+// testingMaskIDsOption is maskIDsOption implemented with the TestingOption interface.
+type testingMaskIDsOption struct {
+	path string
+}
+
+func (m testingMaskIDsOption) Apply(t testing.TB, failNow bool, doc *Document, _ string) {
+	for _, path := range doc.ExpandPath(m.path) {
+		if !strings.HasPrefix(doc.Get(path).String(), "id-") {
+			Fail(t, failNow, "not an ID", "path = %s", path)
+			continue
+		}
+		if !NoError(t, failNow, doc.Set(path, "--* MASKED *--"), "masking path = %s", path) {
+			return
+		}
+	}
+}
+
+func (m testingMaskIDsOption) IsType() OptionType {
+	return OptionTypeModifier
+}
+
+func TestDocument_CustomOption(t *testing.T) {
+	type args struct {
+		want    string
+		got     any
+		options []Option
+	}
+	type given struct {
+		args args
+	}
+	type want struct {
+		failure bool
+	}
+	type test struct {
+		name  string
+		given given
+		want  want
+	}
+	tests := []test{
+		{
+			name: "passes when the custom option modifies the document",
+			given: given{
+				args: args{
+					want: "testdata/document/custom_option.json",
+					got: map[string]any{
+						"id":     "id-1",
+						"name":   "John",
+						"orders": []any{map[string]any{"id": "id-2"}, map[string]any{"id": "id-3"}},
+					},
+					options: []Option{maskIDsOption{path: "id"}, maskIDsOption{path: "orders.#.id"}},
+				},
+			},
+			want: want{failure: false},
+		},
+		{
+			name: "fails when the custom option reports a failure",
+			given: given{
+				args: args{
+					want: "testdata/document/custom_option.json",
+					got: map[string]any{
+						"id":     "1",
+						"name":   "John",
+						"orders": []any{map[string]any{"id": "id-2"}, map[string]any{"id": "id-3"}},
+					},
+					options: []Option{maskIDsOption{path: "id"}, maskIDsOption{path: "orders.#.id"}},
+				},
+			},
+			want: want{failure: true},
+		},
+		{
+			name: "passes when the adapted testing option modifies the document",
+			given: given{
+				args: args{
+					want: "testdata/document/custom_option.json",
+					got: map[string]any{
+						"id":     "id-1",
+						"name":   "John",
+						"orders": []any{map[string]any{"id": "id-2"}, map[string]any{"id": "id-3"}},
+					},
+					options: []Option{
+						FromTestingOption(testingMaskIDsOption{path: "id"}),
+						FromTestingOption(testingMaskIDsOption{path: "orders.#.id"}),
+					},
+				},
+			},
+			want: want{failure: false},
+		},
+		{
+			name: "fails when the adapted testing option reports a failure",
+			given: given{
+				args: args{
+					want: "testdata/document/custom_option.json",
+					got: map[string]any{
+						"id":     "1",
+						"name":   "John",
+						"orders": []any{map[string]any{"id": "id-2"}, map[string]any{"id": "id-3"}},
+					},
+					options: []Option{
+						FromTestingOption(testingMaskIDsOption{path: "id"}),
+						FromTestingOption(testingMaskIDsOption{path: "orders.#.id"}),
+					},
+				},
+			},
+			want: want{failure: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- Given --------------------------------- */
+			tb := newFakeTB(t.Name()) // test result recorder
+
+			/* ---------------------------------- When ---------------------------------- */
+			AssertJSON(tb, tt.given.args.want, tt.given.args.got, tt.given.args.options...)
+
+			/* ---------------------------------- Then ---------------------------------- */
+			require.Equal(t, tt.want.failure, tb.Failed(), "errors: %v", tb.errors)
+		})
+	}
+}
+
+func TestAssertJSON_OptionFailures(t *testing.T) {
+	type args struct {
+		failNow bool
+		options []Option
+	}
+	type given struct {
+		args args
+	}
+	type want struct {
+		// failures are the substrings the single failure report must contain
+		failures []string
+		stopped  bool
+	}
+	type test struct {
+		name  string
+		given given
+		want  want
+	}
+	tests := []test{
+		{
+			name: "reports the failures of all options in a single report",
+			given: given{
+				args: args{
+					options: []Option{
+						WithSkippedFields("age", "colour"),
+						FromTestingOption(testingMaskIDsOption{path: "id"}),
+					},
+				},
+			},
+			want: want{
+				failures: []string{
+					"3 option failure(s)",
+					"path = age: path not found",
+					"path = colour: path not found",
+					"not an ID",
+				},
+			},
+		},
+		{
+			name: "stops execution after reporting when failNow is true",
+			given: given{
+				args: args{
+					failNow: true,
+					options: []Option{
+						WithSkippedFields("age", "colour"),
+						FromTestingOption(testingMaskIDsOption{path: "id"}),
+					},
+				},
+			},
+			want: want{
+				failures: []string{"3 option failure(s)"},
+				stopped:  true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- Given --------------------------------- */
+			tb := newFakeTB(t.Name()) // test result recorder
+			got := map[string]any{"id": "1", "name": "John"}
+
+			/* ---------------------------------- When ---------------------------------- */
+			tb.run(func(tb testing.TB) {
+				if tt.given.args.failNow {
+					RequireJSON(tb, "testdata/document/option_failures.json", got, tt.given.args.options...)
+					return
+				}
+				AssertJSON(tb, "testdata/document/option_failures.json", got, tt.given.args.options...)
+			})
+
+			/* ---------------------------------- Then ---------------------------------- */
+			require.True(t, tb.Failed())
+			require.Equal(t, tt.want.stopped, tb.stopped)
+			require.NotEmpty(t, tb.errors)
+			for _, failure := range tt.want.failures {
+				require.Contains(t, tb.errors[0], failure)
+			}
+		})
+	}
+}
+
+func TestDocument(t *testing.T) {
+	type given struct {
+		json string
+		// modify modifies the document
+		modify func(doc *Document) error
+	}
+	type want struct {
+		json string
+	}
+	type test struct {
+		name  string
+		given given
+		want  want
+	}
+	tests := []test{
+		{
+			name: "sets value",
+			given: given{
+				json: `{"name":"John"}`,
+				modify: func(doc *Document) error {
+					return doc.Set("name", "Jane")
+				},
+			},
+			want: want{json: `{"name":"Jane"}`},
+		},
+		{
+			name: "sets raw value",
+			given: given{
+				json: `{"name":"John"}`,
+				modify: func(doc *Document) error {
+					return doc.SetRaw("colour", []byte(`{"hair":"black"}`))
+				},
+			},
+			want: want{json: `{"name":"John","colour":{"hair":"black"}}`},
+		},
+		{
+			name: "deletes value",
+			given: given{
+				json: `{"name":"John","age":30}`,
+				modify: func(doc *Document) error {
+					return doc.Delete("age")
+				},
+			},
+			want: want{json: `{"name":"John"}`},
+		},
+		{
+			name: "sets values at expanded paths",
+			given: given{
+				json: `{"users":[{"age":30},{"age":31}]}`,
+				modify: func(doc *Document) error {
+					var errs []error
+					for _, path := range doc.ExpandPath("users.#.age") {
+						errs = append(errs, doc.Set(path, doc.Get(path).Int()+1))
+					}
+					return errors.Join(errs...)
+				},
+			},
+			want: want{json: `{"users":[{"age":31},{"age":32}]}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- Given --------------------------------- */
+			doc := NewDocument([]byte(tt.given.json))
+
+			/* ---------------------------------- When ---------------------------------- */
+			err := tt.given.modify(doc)
+
+			/* ---------------------------------- Then ---------------------------------- */
+			require.NoError(t, err)
+			require.Equal(t, tt.want.json, string(doc.Bytes()))
+		})
+	}
+}
+
+func TestFail(t *testing.T) {
+	type given struct {
+		failNow bool
+	}
+	type want struct {
+		stopped bool
+	}
+	type test struct {
+		name  string
+		given given
+		want  want
+	}
+	tests := []test{
+		{
+			name:  "continues execution when failNow is false",
+			given: given{failNow: false},
+			want:  want{stopped: false},
+		},
+		{
+			name:  "stops execution when failNow is true",
+			given: given{failNow: true},
+			want:  want{stopped: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- Given --------------------------------- */
+			tb := newFakeTB(t.Name()) // test result recorder
+			var returned bool
+
+			/* ---------------------------------- When ---------------------------------- */
+			tb.run(func(tb testing.TB) {
+				Fail(tb, tt.given.failNow, "failure")
+				NoError(tb, tt.given.failNow, errors.New("error"))
+				returned = true
+			})
+
+			/* ---------------------------------- Then ---------------------------------- */
+			require.True(t, tb.Failed())
+			require.Equal(t, tt.want.stopped, tb.stopped)
+			require.Equal(t, !tt.want.stopped, returned)
+			if !tt.want.stopped {
+				require.Len(t, tb.errors, 2)
+			}
+		})
+	}
+}
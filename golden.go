@@ -3,7 +3,13 @@ package golden
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"path"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
 	"testing"
@@ -12,9 +18,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/tidwall/gjson"
-	"github.com/tidwall/sjson"
-	gjsonpkg "github.com/tobbstr/golden/gjson"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 // filesWritten keeps track of the files that have been written to. This is to prevent writing to the same file twice.
@@ -28,31 +33,202 @@ const (
 	OptionTypeCheck OptionType = iota
 	// OptionTypeModifier indicates options that modify the JSON structure or content
 	OptionTypeModifier
+	// OptionTypeConfig indicates options that configure how the golden file is compared or written, without
+	// checking or modifying the JSON. They run before all other options.
+	OptionTypeConfig
 )
 
-// golden is a model of the golden file.
-type golden struct {
-	result []byte
-}
-
 // Option is an interface that defines operations on the golden file. It is used to apply modifications or checks
 // to the golden file before comparing it with the actual result.
+//
+// Options do not fail the test themselves. They return errors, and the assertion function reports the errors of all
+// options in a single report, and decides whether the test stops execution.
+//
+// Custom options can be implemented outside this package, using the Document methods to check or modify the JSON.
+//
+// Example: an option that masks the values of the fields at a path
+//
+//	type maskOption struct{ path string }
+//
+//	func (m maskOption) Apply(doc *golden.Document, _ string) error {
+//	    var errs []error
+//	    for _, path := range doc.ExpandPath(m.path) {
+//	        if err := doc.Set(path, "--* MASKED *--"); err != nil {
+//	            errs = append(errs, &golden.OptionError{Path: path, Reason: "masking field value", Err: err})
+//	        }
+//	    }
+//	    return errors.Join(errs...)
+//	}
+//
+//	func (m maskOption) IsType() golden.OptionType { return golden.OptionTypeModifier }
 type Option interface {
 	// Apply executes the option's operation on the golden file.
 	//
 	// Parameters:
-	//   - t: the testing.T value.
+	//   - doc: the document that is compared with the golden file.
+	//   - path: the path to the golden file.
+	//
+	// It returns nil if the operation succeeds. Otherwise, it returns an *OptionError, or several joined with
+	// errors.Join.
+	Apply(doc *Document, path string) error
+
+	// IsType returns the type of this option for sorting purposes.
+	// Check options should run before modifier options to validate the original data.
+	IsType() OptionType
+}
+
+// OptionError describes why an option failed.
+type OptionError struct {
+	// Path is the GJSON path of the value the failure relates to. It is empty if the failure does not relate to a
+	// value.
+	Path string
+	// Reason describes the failure, e.g. "path not found".
+	Reason string
+	// Err is the error that caused the failure, if any.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *OptionError) Error() string {
+	msg := e.Reason
+	if e.Path != "" {
+		msg = "path = " + e.Path + ": " + msg
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the error that caused the failure.
+func (e *OptionError) Unwrap() error {
+	return e.Err
+}
+
+// formatOptionErrors formats the errors returned by options, one per line.
+func formatOptionErrors(err error) string {
+	errs := flattenErrors(err)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d option failure(s):", len(errs))
+	for _, e := range errs {
+		sb.WriteString("\n    ")
+		sb.WriteString(e.Error())
+	}
+	return sb.String()
+}
+
+// flattenErrors returns the errors joined in err with errors.Join, recursively.
+func flattenErrors(err error) []error {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+	var errs []error
+	for _, e := range joined.Unwrap() {
+		errs = append(errs, flattenErrors(e)...)
+	}
+	return errs
+}
+
+// TestingOption is the previous Option interface, whose implementations report failures themselves by marking the
+// test as failed. Use FromTestingOption to pass them to the assertion functions.
+//
+// Deprecated: implement Option instead, returning errors rather than failing the test.
+type TestingOption interface {
+	// Apply executes the option's operation on the golden file.
+	//
+	// Parameters:
+	//   - t: the testing.TB value, e.g. a *testing.T, *testing.B or *testing.F.
 	//   - failNow: if true, if any errors happen the test is marked as failed and stops execution. Otherwise, the test is
 	//     marked as failed, but execution continues.
-	//   - g: a wrapper around the resulting golden file.
+	//   - doc: the document that is compared with the golden file.
 	//   - path: the path to the golden file.
-	Apply(t *testing.T, failNow bool, g *golden, path string)
+	Apply(t testing.TB, failNow bool, doc *Document, path string)
 
 	// IsType returns the type of this option for sorting purposes.
-	// Check options should run before modifier options to validate the original data.
 	IsType() OptionType
 }
 
+// FromTestingOption adapts a TestingOption to the Option interface. The failures the option reports are returned as
+// errors instead of failing the test, and a failure that would stop execution only stops the option.
+func FromTestingOption(opt TestingOption) Option {
+	return testingOptionAdapter{opt: opt}
+}
+
+// testingOptionAdapter implements Option for a TestingOption.
+type testingOptionAdapter struct {
+	opt TestingOption
+}
+
+func (a testingOptionAdapter) Apply(doc *Document, path string) (err error) {
+	rec := &failureRecorder{TB: doc.t}
+	defer func() {
+		if r := recover(); r != nil && r != errFailNow {
+			panic(r)
+		}
+		var errs []error
+		for _, failure := range rec.failures {
+			errs = append(errs, &OptionError{Reason: failure})
+		}
+		if len(errs) == 0 && rec.failed {
+			errs = append(errs, &OptionError{Reason: "option failed"})
+		}
+		err = errors.Join(errs...)
+	}()
+	a.opt.Apply(rec, doc.failNow, doc, path)
+	return nil
+}
+
+func (a testingOptionAdapter) IsType() OptionType {
+	return a.opt.IsType()
+}
+
+// errFailNow is panicked with by failureRecorder.FailNow to stop the option, and recovered by testingOptionAdapter.
+var errFailNow = errors.New("option stopped execution")
+
+// failureRecorder is a testing.TB that records failures instead of failing the test. All other methods are forwarded
+// to the embedded testing.TB.
+type failureRecorder struct {
+	testing.TB
+	failures []string
+	failed   bool
+}
+
+func (r *failureRecorder) Helper() {}
+
+func (r *failureRecorder) Error(args ...any) {
+	r.failures = append(r.failures, strings.TrimSpace(fmt.Sprint(args...)))
+	r.failed = true
+}
+
+func (r *failureRecorder) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, strings.TrimSpace(fmt.Sprintf(format, args...)))
+	r.failed = true
+}
+
+func (r *failureRecorder) Fail() {
+	r.failed = true
+}
+
+func (r *failureRecorder) FailNow() {
+	r.failed = true
+	panic(errFailNow)
+}
+
+func (r *failureRecorder) Fatal(args ...any) {
+	r.Error(args...)
+	r.FailNow()
+}
+
+func (r *failureRecorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	r.FailNow()
+}
+
+func (r *failureRecorder) Failed() bool {
+	return r.failed
+}
+
 // KeepNull overrides the WithSkippedFields' default behaviour for a specific field. It is used when the caller wants to
 // distinguish between a non-null value and a null value, which would otherwise be replaced with "--* SKIPPED *--".
 // With the WithSkippedFields default behaviour the fields are always replaced with skipped, but in some cases it is
@@ -89,12 +265,46 @@ type Option interface {
 // NOTE! Had it not been null, it would have been replaced with "--* SKIPPED *--".
 type KeepNull string
 
+// KeepType overrides the WithSkippedFields' default behaviour for a specific field, by keeping the field's JSON type in
+// the replacement. It is used when the caller doesn't care about the actual value, but wants the test to fail if its
+// type changes, e.g. when a number is suddenly marshalled as a string.
+//
+// The rules for replacing a field's value are as follows:
+//   - If the field's JSON-value is null, then it is left untouched.
+//   - Otherwise it is replaced with "--* SKIPPED <type> *--", where <type> is one of string, number, boolean, object
+//     and array.
+//
+// Example: id field is of an integer Go-type
+//
+// Before calling WithSkippedFields(KeepType("data.user.id")) the JSON is:
+//
+//	{
+//	    "data": {
+//	        "user": {
+//	            "id": 4711,
+//	        }
+//	    }
+//	}
+//
+// After calling WithSkippedFields(KeepType("data.user.id")) the JSON is:
+//
+//	{
+//	    "data": {
+//	        "user": {
+//	            "id": "--* SKIPPED number *--",
+//	        }
+//	    }
+//	}
+//
+// NOTE! KeepType is not supported for XML golden files, whose values have no types.
+type KeepType string
+
 // WithSkippedFields replaces values of the fields with "--* SKIPPED *--".
 // The fields are specified by their GJSON path.
 // See https://github.com/tidwall/gjson/blob/master/SYNTAX.md
 //
-// It accepts either strings or KeepNulls. For strings the values are always replaced by "--* SKIPPED *--".
-// For KeepNulls, see the KeepNull definition for details.
+// It accepts either strings, KeepNulls or KeepTypes. For strings the values are always replaced by "--* SKIPPED *--".
+// For KeepNulls and KeepTypes, see their definitions for details.
 //
 // Example: Replacing the value of the "Name" field with "--* SKIPPED *--"
 //
@@ -119,63 +329,160 @@ type KeepNull string
 //	}
 //
 // skippedFieldsOption implements Option for skipping fields
-type skippedFieldsOption[T string | KeepNull] struct {
+type skippedFieldsOption[T string | KeepNull | KeepType] struct {
 	fields []T
 }
 
-func (s skippedFieldsOption[T]) Apply(t *testing.T, failNow bool, g *golden, _ string) {
+func (s skippedFieldsOption[T]) Apply(doc *Document, _ string) error {
+	var errs []error
 	for _, fld := range s.fields {
 		var path string
-		var keepNull bool
+		var keepNull, keepType bool
 		switch v := any(fld).(type) {
 		case KeepNull:
 			path = string(v)
 			keepNull = true
+		case KeepType:
+			path = string(v)
+			keepNull, keepType = true, true
 		case string:
 			path = v
 			keepNull = false
 		default:
-			if failNow {
-				require.Fail(t, "invalid field type", "field = %T", fld)
-			}
-			assert.Fail(t, "invalid field type", "field = %T", fld)
-			return
+			return &OptionError{Reason: fmt.Sprintf("invalid field type %T", fld)}
 		}
 
-		expandedPaths := gjsonpkg.ExpandPath(g.result, path)
-		for _, expPath := range expandedPaths {
-			gres := gjson.GetBytes(g.result, expPath)
-			if !gres.Exists() {
-				if failNow {
-					require.Fail(t, "path not found", "path = %s", expPath)
-				}
-				assert.Fail(t, "path not found", "path = %s", expPath)
+		if doc.xml != nil {
+			if keepType {
+				errs = append(errs, &OptionError{Path: path, Reason: "KeepType is not supported for XML golden files"})
 				continue
 			}
-			if keepNull && gres.Type == gjson.Null {
+			if err := skipXMLNodes(doc.xml, path, keepNull); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		for _, expPath := range doc.ExpandPath(path) {
+			res := doc.Get(expPath)
+			if !res.Exists() {
+				errs = append(errs, &OptionError{Path: expPath, Reason: "path not found"})
 				continue
 			}
-			res, err := sjson.SetBytes(g.result, expPath, "--* SKIPPED *--")
-			if err != nil {
-				if failNow {
-					require.Fail(t, "setting field value", "path = %s", expPath)
-				}
-				assert.Fail(t, "setting field value", "path = %s", expPath)
+			if keepNull && res.Type == gjson.Null {
 				continue
 			}
-			g.result = res
+			skipped := "--* SKIPPED *--"
+			if keepType {
+				skipped = "--* SKIPPED " + jsonTypeName(res.Value()) + " *--"
+			}
+			if err := doc.Set(expPath, skipped); err != nil {
+				errs = append(errs, &OptionError{Path: expPath, Reason: "setting field value", Err: err})
+			}
 		}
 	}
+	return errors.Join(errs...)
 }
 
 func (s skippedFieldsOption[T]) IsType() OptionType {
 	return OptionTypeModifier
 }
 
-func WithSkippedFields[T string | KeepNull](fields ...T) Option {
+func WithSkippedFields[T string | KeepNull | KeepType](fields ...T) Option {
 	return skippedFieldsOption[T]{fields: fields}
 }
 
+// WithStablePlaceholders replaces values of the fields with placeholders like "<id-1>" and "<id-2>", where equal
+// values get the same placeholder. Unlike WithSkippedFields, this keeps the relationships between non-deterministic
+// values visible in the golden file, e.g. that an order's customerId is the id of its customer.
+// The fields are specified by their GJSON path.
+// See https://github.com/tidwall/gjson/blob/master/SYNTAX.md
+//
+// Placeholders are numbered in the order the fields are given, and within a path in document order. The placeholders
+// are shared by all WithStablePlaceholders options of an assertion. Values are equal if their JSON is equal, so the
+// number 1 and the string "1" get different placeholders. Null values are left untouched.
+//
+// Example: Relating the id of a customer and the customerId of its orders
+//
+// Before calling WithStablePlaceholders("customer.id", "orders.#.id", "orders.#.customerId") the JSON is:
+//
+//	{
+//	    "customer": {
+//	        "id": "7f3c"
+//	    },
+//	    "orders": [
+//	        {
+//	            "id": "a91e",
+//	            "customerId": "7f3c"
+//	        }
+//	    ]
+//	}
+//
+// After calling WithStablePlaceholders("customer.id", "orders.#.id", "orders.#.customerId") the JSON is:
+//
+//	{
+//	    "customer": {
+//	        "id": "<id-1>"
+//	    },
+//	    "orders": [
+//	        {
+//	            "id": "<id-2>",
+//	            "customerId": "<id-1>"
+//	        }
+//	    ]
+//	}
+func WithStablePlaceholders(fields ...string) Option {
+	return stablePlaceholdersOption{fields: fields}
+}
+
+// stablePlaceholdersOption implements Option for replacing values with stable placeholders
+type stablePlaceholdersOption struct {
+	fields []string
+}
+
+func (s stablePlaceholdersOption) Apply(doc *Document, _ string) error {
+	if doc.placeholders == nil {
+		doc.placeholders = make(map[string]string)
+	}
+	placeholder := func(value string) string {
+		p, ok := doc.placeholders[value]
+		if !ok {
+			p = fmt.Sprintf("<id-%d>", len(doc.placeholders)+1)
+			doc.placeholders[value] = p
+		}
+		return p
+	}
+
+	var errs []error
+	for _, path := range s.fields {
+		if doc.xml != nil {
+			if err := placeholdXMLNodes(doc.xml, path, placeholder); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		for _, expPath := range doc.ExpandPath(path) {
+			res := doc.Get(expPath)
+			if !res.Exists() {
+				errs = append(errs, &OptionError{Path: expPath, Reason: "path not found"})
+				continue
+			}
+			if res.Type == gjson.Null {
+				continue
+			}
+			if err := doc.Set(expPath, placeholder(res.Raw)); err != nil {
+				errs = append(errs, &OptionError{Path: expPath, Reason: "setting field value", Err: err})
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s stablePlaceholdersOption) IsType() OptionType {
+	return OptionTypeModifier
+}
+
 // FieldComment is a comment that describes what to look for when inspecting the JSON field. The comment is added to
 // the field specified by its Path.
 type FieldComment struct {
@@ -212,35 +519,37 @@ type fieldCommentsOption struct {
 	fieldComments []FieldComment
 }
 
-func (f fieldCommentsOption) Apply(t *testing.T, failNow bool, g *golden, _ string) {
+func (f fieldCommentsOption) Apply(doc *Document, _ string) error {
 	// Add the comments to the fields
-	var err error
+	var errs []error
+	if doc.xml != nil {
+		for _, fieldComment := range f.fieldComments {
+			if err := commentXMLNodes(doc.xml, fieldComment.Path, fieldComment.Comment); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
 	for _, fieldComment := range f.fieldComments {
-		value := gjson.GetBytes(g.result, fieldComment.Path)
+		value := doc.Get(fieldComment.Path)
 		if !value.Exists() {
-			if failNow {
-				require.Fail(t, "path not found", "path = %s", fieldComment.Path)
-			}
-			assert.Fail(t, "path not found", "path = %s", fieldComment.Path)
+			errs = append(errs, &OptionError{Path: fieldComment.Path, Reason: "path not found"})
 			continue
 		}
-		g.result, err = sjson.SetRawBytes(g.result, fieldComment.Path, []byte(value.Raw+` // `+fieldComment.Comment))
-		if !failNow && !assert.NoError(t, err, "setting field comment for path = %s", fieldComment.Path) {
-			return
-		} else {
-			require.NoError(t, err, "setting field comment for path = %s", fieldComment.Path)
+		if err := doc.SetRaw(fieldComment.Path, []byte(value.Raw+` // `+fieldComment.Comment)); err != nil {
+			errs = append(errs, &OptionError{Path: fieldComment.Path, Reason: "setting field comment", Err: err})
 		}
 	}
 
 	// Fix misplaced commas. When the field value is replaced, if the line ends with a comma, the comment is added
 	// before the comma. This function moves the comma before the comment.
-	correctedJSON, err := correctMisplacedCommas(g.result)
-	if !failNow && !assert.NoError(t, err, "correcting misplaced commas in JSON") {
-		return
-	} else {
-		require.NoError(t, err, "correcting misplaced commas in JSON")
+	correctedJSON, err := correctMisplacedCommas(doc.result)
+	if err != nil {
+		errs = append(errs, &OptionError{Reason: "correcting misplaced commas in JSON", Err: err})
+		return errors.Join(errs...)
 	}
-	g.result = correctedJSON
+	doc.result = correctedJSON
+	return errors.Join(errs...)
 }
 
 func (f fieldCommentsOption) IsType() OptionType {
@@ -303,8 +612,13 @@ type fileCommentOption struct {
 	comment string
 }
 
-func (f fileCommentOption) Apply(t *testing.T, _ bool, g *golden, _ string) {
-	g.result = append([]byte("/*\n"+f.comment+"\n*/\n\n"), g.result...)
+func (f fileCommentOption) Apply(doc *Document, _ string) error {
+	if doc.xml != nil {
+		addXMLFileComment(doc.xml, f.comment)
+		return nil
+	}
+	doc.result = append([]byte("/*\n"+f.comment+"\n*/\n\n"), doc.result...)
+	return nil
 }
 
 func (f fileCommentOption) IsType() OptionType {
@@ -322,11 +636,17 @@ func WithFileComment(comment string) Option {
 // "UPDATE_GOLDENS" to "1" to update the golden files, when running the tests.
 //
 // Example: UPDATE_GOLDENS=1 go test ./...
+//
+// Comments in the existing golden file that were added by hand, i.e. not by WithFieldComments or WithFileComment, are
+// re-attached to the same GJSON paths in the updated golden file. If a path no longer exists, its comment is dropped
+// and the test is marked as failed, so that the comment can be moved by hand.
 // updateGoldenFilesOption implements Option for updating golden files
 type updateGoldenFilesOption struct{}
 
-func (u updateGoldenFilesOption) Apply(t *testing.T, failNow bool, g *golden, path string) {
-	writeGoldenFile(t, failNow, path, g.result)
+func (u updateGoldenFilesOption) Apply(doc *Document, _ string) error {
+	// The golden file is written once all options are applied and the result is rendered, see updateGoldenFile.
+	doc.update = true
+	return nil
 }
 
 func (u updateGoldenFilesOption) IsType() OptionType {
@@ -337,6 +657,108 @@ func UpdateGoldenFiles() Option {
 	return updateGoldenFilesOption{}
 }
 
+// WithSemanticCompare compares the golden file and the actual result by their parsed JSON values, instead of by their
+// text. JSONC comments are stripped before parsing, so the comparison ignores differences in indentation, key order
+// and comments, and only fails on actual data differences. This makes it possible to keep hand-formatted or
+// hand-commented golden files.
+//
+// NOTE! When the golden file is updated, it is still written in the library's own format.
+// semanticCompareOption implements Option for comparing golden files semantically
+type semanticCompareOption struct{}
+
+func (s semanticCompareOption) Apply(doc *Document, _ string) error {
+	doc.semantic = true
+	return nil
+}
+
+func (s semanticCompareOption) IsType() OptionType {
+	return OptionTypeConfig
+}
+
+func WithSemanticCompare() Option {
+	return semanticCompareOption{}
+}
+
+// CreateMissingGoldenFiles creates the golden file from the actual result, including any missing parent directories,
+// when it does not exist yet. The test is then marked as failed with a "golden file created" message, so that the new
+// golden file gets reviewed before the test is re-run. Existing golden files are never touched.
+//
+// This removes the need to run new tests twice, once to create the golden file and once to compare with it.
+//
+// NOTE! This option should normally not be invoked directly. Instead, set the environment variable
+// "CREATE_GOLDENS" to "1" to create missing golden files, when running the tests.
+//
+// Example: CREATE_GOLDENS=1 go test ./...
+// createMissingGoldenFilesOption implements Option for creating missing golden files
+type createMissingGoldenFilesOption struct{}
+
+func (c createMissingGoldenFilesOption) Apply(doc *Document, _ string) error {
+	doc.createMissing = true
+	return nil
+}
+
+func (c createMissingGoldenFilesOption) IsType() OptionType {
+	return OptionTypeConfig
+}
+
+func CreateMissingGoldenFiles() Option {
+	return createMissingGoldenFilesOption{}
+}
+
+// WithGoldenDir sets the root directory of the golden files whose paths are derived from the test name by
+// AssertJSONAuto, RequireJSONAuto and Path. The default is "testdata".
+// goldenDirOption implements Option for setting the golden files' root directory
+type goldenDirOption struct {
+	dir string
+}
+
+func (o goldenDirOption) Apply(*Document, string) error {
+	return nil
+}
+
+func (o goldenDirOption) IsType() OptionType {
+	return OptionTypeConfig
+}
+
+func WithGoldenDir(dir string) Option {
+	return goldenDirOption{dir: dir}
+}
+
+// WriteActualFiles writes the actual result, i.e. the result after all options have been applied, to a file with the
+// ".actual" suffix when the comparison with the golden file fails. This makes the full output available for
+// inspection, e.g. by uploading it as a CI artifact or diffing it locally with your own tools. When the comparison
+// passes, any stale ".actual" file is removed.
+//
+// Parameters:
+//   - dir: the artifacts directory to write the files to, keeping the golden file's relative path. If empty, the
+//     file is written next to the golden file.
+//
+// Example: the actual result for "testdata/get_person/happy_path.value.json" is written to
+// "testdata/get_person/happy_path.value.json.actual", or "<dir>/testdata/get_person/happy_path.value.json.actual".
+//
+// NOTE! This option should normally not be invoked directly. Instead, set the environment variable "WRITE_ACTUALS"
+// to "1" to write the files next to the golden files, or set "ACTUALS_DIR" to the artifacts directory.
+//
+// Example: ACTUALS_DIR=/tmp/artifacts go test ./...
+// writeActualFilesOption implements Option for writing the actual results to files
+type writeActualFilesOption struct {
+	dir string
+}
+
+func (w writeActualFilesOption) Apply(doc *Document, _ string) error {
+	doc.writeActual = true
+	doc.actualDir = w.dir
+	return nil
+}
+
+func (w writeActualFilesOption) IsType() OptionType {
+	return OptionTypeConfig
+}
+
+func WriteActualFiles(dir string) Option {
+	return writeActualFilesOption{dir: dir}
+}
+
 // CheckNotZeroTime checks if the time at the specified path is not zero, and fails the test if the time is zero.
 //
 // Parameters:
@@ -350,41 +772,19 @@ type checkNotZeroTimeOption struct {
 	layout string
 }
 
-func (c checkNotZeroTimeOption) Apply(t *testing.T, failNow bool, g *golden, _ string) {
-	expandedPaths := gjsonpkg.ExpandPath(g.result, c.path)
-	for _, expPath := range expandedPaths {
-		res := gjson.GetBytes(g.result, expPath)
-		if !res.Exists() {
-			if failNow {
-				require.Fail(t, "path not found in JSON", "path = %s", expPath)
-			}
-			assert.Fail(t, "path not found in JSON", "path = %s", expPath)
-			return
-		}
-		if res.Type != gjson.String {
-			if failNow {
-				require.Fail(t, "path's value is not a string", "path = %s", expPath)
-			}
-			assert.Fail(t, "path's value is not a string", "path = %s", expPath)
-			return
-		}
-
-		tide, err := time.Parse(c.layout, res.String())
+func (c checkNotZeroTimeOption) Apply(doc *Document, _ string) error {
+	var errs []error
+	for _, expPath := range doc.ExpandPath(c.path) {
+		tide, err := timeAt(doc, expPath, c.layout)
 		if err != nil {
-			if failNow {
-				require.Fail(t, "parsing time", "path = %s", expPath)
-			}
-			assert.Fail(t, "parsing time", "path = %s", expPath)
-			return
+			errs = append(errs, err)
+			continue
 		}
-
 		if tide.IsZero() {
-			if failNow {
-				require.Fail(t, "time is zero", "path = %s", expPath)
-			}
-			assert.Fail(t, "time is zero", "path = %s", expPath)
+			errs = append(errs, &OptionError{Path: expPath, Reason: "time is zero"})
 		}
 	}
+	return errors.Join(errs...)
 }
 
 func (c checkNotZeroTimeOption) IsType() OptionType {
@@ -408,63 +808,20 @@ type checkEqualTimesOption struct {
 	a, b, layout string
 }
 
-func (c checkEqualTimesOption) Apply(t *testing.T, failNow bool, g *golden, _ string) {
-	aRes := gjson.GetBytes(g.result, c.a)
-	if !aRes.Exists() {
-		if failNow {
-			require.Fail(t, "a not found in JSON", "path = %s", c.a)
-		}
-		assert.Fail(t, "a not found in JSON", "path = %s", c.a)
-		return
-	}
-	if aRes.Type != gjson.String {
-		if failNow {
-			require.Fail(t, "a's value is not a string", "path = %s", c.a)
-		}
-		assert.Fail(t, "a's value is not a string", "path = %s", c.a)
-		return
-	}
-
-	aTide, err := time.Parse(c.layout, aRes.String())
-	if err != nil {
-		if failNow {
-			require.Fail(t, "parsing a's time", "path = %s", c.a)
-		}
-		assert.Fail(t, "parsing a's time", "path = %s", c.a)
-		return
-	}
-
-	bRes := gjson.GetBytes(g.result, c.b)
-	if !bRes.Exists() {
-		if failNow {
-			require.Fail(t, "b not found in JSON", "path = %s", c.b)
-		}
-		assert.Fail(t, "b not found in JSON", "path = %s", c.b)
-		return
-	}
-	if bRes.Type != gjson.String {
-		if failNow {
-			require.Fail(t, "b's value is not a string", "path = %s", c.b)
-		}
-		assert.Fail(t, "b's value is not a string", "path = %s", c.b)
-		return
-	}
-
-	bTide, err := time.Parse(c.layout, bRes.String())
-	if err != nil {
-		if failNow {
-			require.Fail(t, "parsing b's time", "path = %s", c.b)
-		}
-		assert.Fail(t, "parsing b's time", "path = %s", c.b)
-		return
+func (c checkEqualTimesOption) Apply(doc *Document, _ string) error {
+	aTide, aErr := timeAt(doc, c.a, c.layout)
+	bTide, bErr := timeAt(doc, c.b, c.layout)
+	if aErr != nil || bErr != nil {
+		return errors.Join(aErr, bErr)
 	}
 
 	if !aTide.Equal(bTide) {
-		if failNow {
-			require.Fail(t, "times are not equal", "a = %s, b = %s", aTide.String(), bTide.String())
+		return &OptionError{
+			Path:   c.a,
+			Reason: fmt.Sprintf("times are not equal, a = %s, b = %s (path = %s)", aTide.String(), bTide.String(), c.b),
 		}
-		assert.Fail(t, "times are not equal", "a = %s, b = %s", aTide.String(), bTide.String())
 	}
+	return nil
 }
 
 func (c checkEqualTimesOption) IsType() OptionType {
@@ -475,39 +832,199 @@ func CheckEqualTimes(a, b, layout string) Option {
 	return checkEqualTimesOption{a: a, b: b, layout: layout}
 }
 
+// timeAt parses the time at the concrete path in the document.
+func timeAt(doc *Document, path, layout string) (time.Time, error) {
+	res := doc.Get(path)
+	if !res.Exists() {
+		return time.Time{}, &OptionError{Path: path, Reason: "path not found in JSON"}
+	}
+	if res.Type != gjson.String {
+		return time.Time{}, &OptionError{Path: path, Reason: "path's value is not a string"}
+	}
+	tide, err := time.Parse(layout, res.String())
+	if err != nil {
+		return time.Time{}, &OptionError{Path: path, Reason: "parsing time", Err: err}
+	}
+	return tide, nil
+}
+
 // AssertJSON compares the expected JSON (want) with the actual value (got), and if they are different it marks
 // the test as failed, but continues execution. The expected JSON is read from a golden file.
 //
+// Protobuf messages and gRPC status errors are marshalled with protojson, see WithProtoNames and WithEmitUnpopulated.
+// Already serialized JSON, e.g. an HTTP response body, is indented without being marshalled again, see RawJSON.
+// Everything else is marshalled with encoding/json.
+//
 // To update the golden file with the actual value instead of comparing with it, set the environment variable
 // "UPDATE_GOLDENS" to "1" when running the tests.
 //
 // Example: UPDATE_GOLDENS=1 go test ./...
-func AssertJSON(t *testing.T, want string, got any, opts ...Option) {
+func AssertJSON(t testing.TB, want string, got any, opts ...Option) {
 	t.Helper()
-	if os.Getenv("UPDATE_GOLDENS") == "1" {
-		opts = append(opts, UpdateGoldenFiles())
-	}
-	compareJSON(t, false, want, got, opts...)
+	compareJSON(t, false, want, got, append(opts, envOptions(t, want)...)...)
 }
 
 // RequireJSON does the same as AssertJSON, but if the expected JSON (want) and the actual value (got) are different,
 // it marks the test as failed and stops execution.
-func RequireJSON(t *testing.T, want string, got any, opts ...Option) {
+func RequireJSON(t testing.TB, want string, got any, opts ...Option) {
 	t.Helper()
-	if os.Getenv("UPDATE_GOLDENS") == "1" {
+	compareJSON(t, true, want, got, append(opts, envOptions(t, want)...)...)
+}
+
+// AssertJSONAuto does the same as AssertJSON, but derives the path to the golden file from the test name, see Path.
+// This gives table-driven tests one golden file per test case without any bookkeeping.
+//
+// Example: in the subtest "TestGetPerson/happy path" the golden file is "testdata/TestGetPerson/happy_path.json".
+func AssertJSONAuto(t testing.TB, got any, opts ...Option) {
+	t.Helper()
+	AssertJSON(t, Path(t, opts...), got, opts...)
+}
+
+// RequireJSONAuto does the same as RequireJSON, but derives the path to the golden file from the test name, see Path.
+func RequireJSONAuto(t testing.TB, got any, opts ...Option) {
+	t.Helper()
+	RequireJSON(t, Path(t, opts...), got, opts...)
+}
+
+// Path returns the path to the golden file for the test, derived from the test and subtest names. Each name becomes
+// a path element, with characters other than letters, digits, '.', '-' and '_' replaced by '_'. The root directory
+// is "testdata", unless WithGoldenDir is among the options. The extension is ".jsonc" when WithFieldComments or
+// WithFileComment are among the options, since they make the file invalid JSON, and ".json" otherwise.
+//
+// Example: in the subtest "TestGetPerson/happy path" the path is "testdata/TestGetPerson/happy_path.json".
+//
+// NOTE! A test that compares more than one value with golden files needs a path per value. Use Path to derive them,
+// e.g. Path(t) + ".status.json".
+func Path(t testing.TB, opts ...Option) string {
+	dir := "testdata"
+	ext := ".json"
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case goldenDirOption:
+			dir = o.dir
+		case fieldCommentsOption, fileCommentOption:
+			ext = ".jsonc"
+		}
+	}
+
+	elems := []string{dir}
+	for _, name := range strings.Split(t.Name(), "/") {
+		elems = append(elems, sanitizePathElement(name))
+	}
+	return filepath.Join(elems...) + ext
+}
+
+// sanitizePathElement replaces characters that are unsafe in file names with '_'.
+func sanitizePathElement(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '-' || c == '_') {
+			b[i] = '_'
+		}
+	}
+	// Prevent "", "." and ".." from being interpreted as directories.
+	if strings.Trim(string(b), ".") == "" {
+		return strings.Repeat("_", len(b)+1)
+	}
+	return string(b)
+}
+
+// envOptions returns the options enabled by environment variables for the golden file at path.
+func envOptions(t testing.TB, path string) []Option {
+	t.Helper()
+	var opts []Option
+	if shouldUpdateGoldenFile(t, path) {
 		opts = append(opts, UpdateGoldenFiles())
 	}
-	compareJSON(t, true, want, got, opts...)
+	if os.Getenv("CREATE_GOLDENS") == "1" {
+		opts = append(opts, CreateMissingGoldenFiles())
+	}
+	if dir := os.Getenv("ACTUALS_DIR"); dir != "" {
+		opts = append(opts, WriteActualFiles(dir))
+	} else if os.Getenv("WRITE_ACTUALS") == "1" {
+		opts = append(opts, WriteActualFiles(""))
+	}
+	return opts
+}
+
+// shouldUpdateGoldenFile reports whether the golden file at path should be updated. This is the case when the
+// environment variable "UPDATE_GOLDENS" is set to "1", and the test and the path match the optional filters:
+//   - UPDATE_GOLDENS_MATCH: a regular expression matched against the test name, e.g. "TestGetPerson/happy_path".
+//   - UPDATE_GOLDENS_PATH: a glob pattern matched against the path, e.g. "testdata/get_person/*.json". In addition to
+//     the syntax supported by path.Match, "**" matches any number of directories.
+//
+// Invalid filters mark the test as failed, and no golden files are updated.
+func shouldUpdateGoldenFile(t testing.TB, path string) bool {
+	t.Helper()
+	if os.Getenv("UPDATE_GOLDENS") != "1" {
+		return false
+	}
+
+	if pattern := os.Getenv("UPDATE_GOLDENS_MATCH"); pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if !assert.NoError(t, err, "compiling UPDATE_GOLDENS_MATCH = %s", pattern) {
+			return false
+		}
+		if !re.MatchString(t.Name()) {
+			return false
+		}
+	}
+
+	if pattern := os.Getenv("UPDATE_GOLDENS_PATH"); pattern != "" {
+		matched, err := matchGlob(pattern, path)
+		if !assert.NoError(t, err, "matching UPDATE_GOLDENS_PATH = %s", pattern) {
+			return false
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchGlob reports whether the whole name matches the glob pattern. Both are compared using forward slashes as
+// separators. The pattern supports the syntax of path.Match, and "**" which matches any number of directories.
+func matchGlob(pattern, name string) (bool, error) {
+	pattern = filepath.ToSlash(pattern)
+	if _, err := path.Match(pattern, ""); err != nil {
+		return false, err
+	}
+	return matchGlobSegments(strings.Split(pattern, "/"), strings.Split(filepath.ToSlash(name), "/")), nil
+}
+
+// matchGlobSegments reports whether the segments of a name match those of a valid glob pattern. Each segment is
+// matched with path.Match, except "**", which matches any number of segments.
+func matchGlobSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchGlobSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	matched, _ := path.Match(pattern[0], segments[0])
+	return matched && matchGlobSegments(pattern[1:], segments[1:])
 }
 
-// sortOptions sorts the provided options so that check functions run before modifier functions.
-// This ensures that validation operations happen on the original JSON before any modifications.
+// sortOptions sorts the provided options so that config functions run first, and check functions run before modifier
+// functions. This ensures that validation operations happen on the original JSON before any modifications.
 func sortOptions(opts []Option) []Option {
+	var configOpts []Option
 	var checkOpts []Option
 	var modifierOpts []Option
 
 	for _, opt := range opts {
 		switch opt.IsType() {
+		case OptionTypeConfig:
+			configOpts = append(configOpts, opt)
 		case OptionTypeCheck:
 			checkOpts = append(checkOpts, opt)
 		case OptionTypeModifier:
@@ -518,72 +1035,329 @@ func sortOptions(opts []Option) []Option {
 		}
 	}
 
-	// Combine check functions first, then modifier functions
-	result := make([]Option, 0, len(checkOpts)+len(modifierOpts))
+	// Combine config functions first, then check functions, then modifier functions
+	result := make([]Option, 0, len(configOpts)+len(checkOpts)+len(modifierOpts))
+	result = append(result, configOpts...)
 	result = append(result, checkOpts...)
 	result = append(result, modifierOpts...)
 	return result
 }
 
-func compareJSON(t *testing.T, failNow bool, want string, got any, opts ...Option) {
-	t.Helper()
+// RawJSON is JSON that is already serialized, e.g. an HTTP response body. Passed as got, it is indented instead of
+// being marshalled again, so the golden file holds the JSON itself rather than a JSON string or base64 encoding of it.
+//
+// Wrapping got in RawJSON is only needed for JSON that is not an object or an array, e.g. RawJSON(`"text"`), since a
+// []byte or string holding a JSON object or array, a json.RawMessage and an io.Reader are all treated as raw JSON.
+//
+// Empty raw JSON, e.g. a nil RawJSON or json.RawMessage, is compared as null, like json.Marshal marshals a nil
+// json.RawMessage.
+//
+// Example: AssertJSON(t, "testdata/get_person.json", RawJSON(rec.Body.Bytes()))
+type RawJSON []byte
 
-	// Handle gRPC status errors by extracting their protobuf representation, as JSON marshaling skips unexported fields.
+// marshalGot marshals got to indented JSON:
+//   - gRPC status errors are marshalled as their google.rpc.Status message, since JSON marshaling skips unexported
+//     fields.
+//   - Protobuf messages are marshalled with protojson, see marshalProto.
+//   - Raw JSON, i.e. RawJSON, json.RawMessage, the content of an io.Reader, and a []byte or string holding a JSON object
+//     or array, is indented without being marshalled again. The order of object keys is kept.
+//   - Everything else is marshalled with encoding/json.
+func marshalGot(got any, opts []Option) ([]byte, error) {
 	if err, ok := got.(error); ok {
 		if st, ok := status.FromError(err); ok {
 			got = st.Proto()
 		}
 	}
 
-	var gotBytes []byte
-	gotBytes, err := json.MarshalIndent(got, "", "    ")
-	if !failNow && !assert.NoError(t, err, "marshalling got") {
+	switch v := got.(type) {
+	case proto.Message:
+		return marshalProto(v, opts)
+	case RawJSON:
+		return indentJSON(v)
+	case json.RawMessage:
+		return indentJSON(v)
+	case io.Reader:
+		data, err := io.ReadAll(v)
+		if err != nil {
+			return nil, fmt.Errorf("reading got: %w", err)
+		}
+		return indentJSON(data)
+	case []byte:
+		if isJSONContainer(v) {
+			return indentJSON(v)
+		}
+	case string:
+		if isJSONContainer([]byte(v)) {
+			return indentJSON([]byte(v))
+		}
+	}
+	return json.MarshalIndent(got, "", "    ")
+}
+
+// isJSONContainer reports whether the data is a valid JSON object or array.
+func isJSONContainer(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed)
+}
+
+// indentJSON indents the raw JSON with four spaces, like json.MarshalIndent, replacing its original whitespace. Empty
+// raw JSON is null.
+func indentJSON(data []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return []byte("null"), nil
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, bytes.TrimSpace(data), "", "    "); err != nil {
+		return nil, fmt.Errorf("invalid raw JSON: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func compareJSON(t testing.TB, failNow bool, want string, got any, opts ...Option) {
+	t.Helper()
+
+	gotBytes, err := marshalGot(got, opts)
+	if !NoError(t, failNow, err, "marshalling got") {
 		return
-	} else {
-		require.NoError(t, err, "marshalling got")
 	}
 
-	g := &golden{result: gotBytes}
+	compareGolden(t, failNow, want, &Document{result: gotBytes}, jsonFormat, opts...)
+}
+
+// goldenFormat describes how the document is rendered to a golden file of a given format, and how golden files of
+// that format are compared.
+type goldenFormat struct {
+	// render renders the document, after all options are applied, to the format.
+	render func(doc *Document) ([]byte, error)
+	// mergeComments re-attaches the comments in the old golden file to the same paths in the new one, and returns the
+	// comments whose paths no longer exist.
+	mergeComments func(oldData, newData []byte) ([]byte, []jsoncComment, error)
+	// diff returns the structural differences between the golden file and the actual result. The returned bool is
+	// false when either of them cannot be parsed.
+	diff func(golden, actual []byte) ([]jsonDiff, bool)
+	// report describes how the golden file and the actual result differ, when diff finds no structural differences.
+	// If nil, they are compared as strings.
+	report func(golden, actual []byte) string
+	// canonical is true when the golden file and the actual result are equal if diff finds no structural differences,
+	// whether or not WithSemanticCompare is used.
+	canonical bool
+}
+
+// jsonFormat is the format of JSON and JSONC golden files.
+var jsonFormat = goldenFormat{
+	render:        renderUnchanged,
+	mergeComments: mergeComments,
+	diff:          diffGolden,
+}
+
+// renderUnchanged renders the document as is.
+func renderUnchanged(doc *Document) ([]byte, error) {
+	return doc.result, nil
+}
+
+// compareGolden applies the options to the document, renders it to the format and compares it with the golden file at
+// want.
+func compareGolden(t testing.TB, failNow bool, want string, doc *Document, format goldenFormat, opts ...Option) {
+	t.Helper()
+
+	doc.t, doc.failNow = t, failNow
 
-	// Sort options so that check functions run before modifier functions
-	sortedOpts := sortOptions(opts)
-	for _, opt := range sortedOpts {
-		opt.Apply(t, failNow, g, want)
+	// Sort options so that check functions run before modifier functions. All options are applied before the failures
+	// are reported, so that they all end up in a single report.
+	var errs []error
+	for _, opt := range sortOptions(opts) {
+		if err := opt.Apply(doc, want); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	got, renderErr := format.render(doc)
+	if renderErr != nil {
+		errs = append(errs, &OptionError{Reason: "rendering golden file", Err: renderErr})
+	} else if doc.update {
+		var err error
+		if got, err = updateGoldenFile(format, want, got); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		Fail(t, failNow, "applying options", "golden file = %s\n%s", want, formatOptionErrors(errors.Join(errs...)))
+	}
+	if renderErr != nil {
+		return
 	}
 
 	goldenBytes, err := os.ReadFile(want)
+	if os.IsNotExist(err) && doc.createMissing {
+		createGoldenFile(t, failNow, want, got)
+		return
+	}
+	if err != nil {
+		writeActualFile(t, doc, want, got)
+	}
 	if !failNow && !assert.NoError(t, err, "reading golden file") {
 		return
 	} else {
 		require.NoError(t, err, "reading golden file")
 	}
 
+	if doc.normalizeLineEndings {
+		goldenBytes = normalizeLineEndings(goldenBytes)
+		got = normalizeLineEndings(got)
+	}
+
+	if bytes.Equal(goldenBytes, got) {
+		removeActualFile(t, doc, want)
+		return
+	}
+
+	// Comments in the golden file that updating it would keep, e.g. hand-written ones, are not differences, so that
+	// the golden file still matches after it has been updated.
+	if merged, _, err := format.mergeComments(goldenBytes, got); err == nil && bytes.Equal(goldenBytes, merged) {
+		removeActualFile(t, doc, want)
+		return
+	}
+
+	diffs, parsed := format.diff(goldenBytes, got)
+
+	// Differences in formatting or comments only are accepted when comparing semantically or canonically.
+	if (doc.semantic || format.canonical) && parsed && len(diffs) == 0 {
+		removeActualFile(t, doc, want)
+		return
+	}
+
+	// Write the actual result before reporting the failure, since reporting stops execution when failNow is true.
+	writeActualFile(t, doc, want, got)
+
+	// Report the differences as a list of GJSON paths when both documents can be parsed, since a text diff of a large
+	// document buries the actual change. Fall back to the text diff when they cannot be parsed, or when they only
+	// differ in formatting or comments.
+	if parsed && len(diffs) > 0 {
+		if failNow {
+			require.Fail(t, "comparing with golden file", "golden file = %s\n%s", want, formatDiffs(diffs))
+		}
+		assert.Fail(t, "comparing with golden file", "golden file = %s\n%s", want, formatDiffs(diffs))
+		return
+	}
+
+	if format.report != nil {
+		Fail(t, failNow, "comparing with golden file", "golden file = %s\n%s", want, format.report(goldenBytes, got))
+		return
+	}
+
 	if failNow {
-		require.Equal(t, string(goldenBytes), string(g.result), "comparing with golden file")
+		require.Equal(t, string(goldenBytes), string(got), "comparing with golden file")
 	} else {
-		assert.Equal(t, string(goldenBytes), string(g.result), "comparing with golden file")
+		assert.Equal(t, string(goldenBytes), string(got), "comparing with golden file")
+	}
+}
+
+// updateGoldenFile writes the rendered result to the golden file at path, and returns the written result. Comments in
+// the existing golden file are re-attached to the same paths, and an error is returned for each comment whose path no
+// longer exists. Nothing is re-attached if the golden file does not exist or cannot be parsed.
+func updateGoldenFile(format goldenFormat, path string, got []byte) ([]byte, error) {
+	var lost []jsoncComment
+	if existing, err := os.ReadFile(path); err == nil {
+		if merged, l, err := format.mergeComments(existing, got); err == nil {
+			got, lost = merged, l
+		}
+	}
+	if err := writeGoldenFile(path, got); err != nil {
+		return got, &OptionError{Reason: "writing golden file", Err: err}
+	}
+	var errs []error
+	for _, c := range lost {
+		errs = append(errs, &OptionError{
+			Path:   rootPath(c.path),
+			Reason: fmt.Sprintf("comment dropped, its path no longer exists: %s", c.text),
+		})
+	}
+	return got, errors.Join(errs...)
+}
+
+// actualFilePath returns the path to the ".actual" file for the golden file at path.
+func actualFilePath(doc *Document, path string) string {
+	if doc.actualDir == "" {
+		return path + ".actual"
+	}
+	// Rooting the path before joining keeps files for golden paths such as "../x.json" inside the directory.
+	return filepath.Join(doc.actualDir, filepath.Clean("/"+path)+".actual")
+}
+
+// writeActualFile writes the actual result to the ".actual" file for the golden file at path, if enabled.
+func writeActualFile(t testing.TB, doc *Document, path string, got []byte) {
+	t.Helper()
+	if !doc.writeActual {
+		return
+	}
+	actualPath := actualFilePath(doc, path)
+	if err := os.MkdirAll(filepath.Dir(actualPath), 0755); err != nil {
+		assert.NoError(t, err, "creating directory for actual file = %s", actualPath)
+		return
 	}
+	assert.NoError(t, os.WriteFile(actualPath, got, 0644), "writing actual file = %s", actualPath)
 }
 
-func writeGoldenFile(t *testing.T, required bool, path string, got []byte) {
+// removeActualFile removes a stale ".actual" file for the golden file at path, if enabled.
+func removeActualFile(t testing.TB, doc *Document, path string) {
 	t.Helper()
+	if !doc.writeActual {
+		return
+	}
+	actualPath := actualFilePath(doc, path)
+	if err := os.Remove(actualPath); err != nil && !os.IsNotExist(err) {
+		assert.NoError(t, err, "removing stale actual file = %s", actualPath)
+	}
+}
+
+func writeGoldenFile(path string, got []byte) error {
 	// check for duplicate writes
 	if _, written := filesWritten.Load(path); written {
-		if !required {
-			assert.Equal(t, false, written, "writing golden file = %s: attempting to write to the same file twice", path)
-			return
-		}
-		require.Equal(t, false, written, "writing golden file = %s: attempting to write to the same file twice", path)
-		return
+		return errors.New("attempting to write to the same file twice")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, got, 0644); err != nil {
+		return err
 	}
 
-	err := os.WriteFile(path, got, 0644)
-	if !required {
-		assert.NoError(t, err, "writing golden file = %s", path)
+	// mark the file as written
+	filesWritten.Store(path, struct{}{})
+	return nil
+}
+
+// createGoldenFile creates the golden file at path, including its parent directories, and marks the test as failed
+// so that the new file gets reviewed. An existing file is never overwritten.
+func createGoldenFile(t testing.TB, required bool, path string, got []byte) {
+	t.Helper()
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err == nil {
+		err = writeNewFile(path, got)
+	}
+	if !required && !assert.NoError(t, err, "creating golden file = %s", path) {
 		return
 	}
-	require.NoError(t, err, "writing golden file = %s", path)
+	require.NoError(t, err, "creating golden file = %s", path)
 
 	// mark the file as written
 	filesWritten.Store(path, struct{}{})
+
+	if required {
+		require.Fail(t, "golden file created", "golden file = %s: review it and re-run the test", path)
+	}
+	assert.Fail(t, "golden file created", "golden file = %s: review it and re-run the test", path)
+}
+
+// writeNewFile writes data to a new file at path. It fails if the file already exists.
+func writeNewFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
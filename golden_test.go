@@ -1,12 +1,21 @@
 package golden
 
 import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/apipb"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 func TestAssertJSON_UpdateFlag(t *testing.T) {
@@ -48,6 +57,25 @@ func TestAssertJSON_UpdateFlag(t *testing.T) {
 				json: `{
     "age": 30,
     "name": "John"
+}`,
+				goldenFileUpdated: true,
+			},
+		},
+		{
+			name: "preserves hand-written comments when update flag is set to true",
+			given: given{
+				args: args{
+					want: "testdata/assert_json_update_flag/preserves_comments.jsonc",
+					got:  map[string]any{"name": "John", "age": 30},
+				},
+				update: true,
+			},
+			want: want{
+				json: `// Reviewed by hand
+
+{
+    "age": 30, // Should be the age in years
+    "name": "John"
 }`,
 				goldenFileUpdated: true,
 			},
@@ -76,12 +104,10 @@ func TestAssertJSON_UpdateFlag(t *testing.T) {
 			if tt.given.update {
 				tt.given.args.options = append(tt.given.args.options, UpdateGoldenFiles())
 			}
+			// Update a copy of the golden file, since a golden file is only written once per test run
 			initialGoldenFile := readFile(t, tt.given.args.want)
-
-			// Restore the initial golden file if it will be updated
-			if tt.want.goldenFileUpdated {
-				defer writeFile(t, tt.given.args.want, initialGoldenFile)
-			}
+			tt.given.args.want = filepath.Join(t.TempDir(), filepath.Base(tt.given.args.want))
+			writeFile(t, tt.given.args.want, initialGoldenFile)
 
 			tt.given.args.t = t
 
@@ -110,6 +136,23 @@ func TestAssertJSON_UpdateFlag(t *testing.T) {
 	}
 }
 
+func TestAssertJSON_HandWrittenComments(t *testing.T) {
+	/* ---------------------------------- Given --------------------------------- */
+	want := filepath.Join(t.TempDir(), "preserves_comments.jsonc")
+	writeFile(t, want, readFile(t, "testdata/assert_json_update_flag/preserves_comments.jsonc"))
+	got := map[string]any{"name": "John", "age": 30}
+	updateTB := newFakeTB(t.Name()) // test result recorder
+	AssertJSON(updateTB, want, got, UpdateGoldenFiles())
+	requireFailures(t, updateTB)
+	tb := newFakeTB(t.Name()) // test result recorder
+
+	/* ---------------------------------- When ---------------------------------- */
+	AssertJSON(tb, want, got)
+
+	/* ---------------------------------- Then ---------------------------------- */
+	requireFailures(t, tb)
+}
+
 func TestAssertJSON_Failure(t *testing.T) {
 	type args struct {
 		t       *testing.T
@@ -165,6 +208,57 @@ func TestAssertJSON_Failure(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "test fails when replacing non-existent field with a stable placeholder",
+			given: given{
+				args: args{
+					want: "testdata/assert_json_failure/empty.json",
+					got: map[string]any{
+						"name": "John",
+					},
+					options: []Option{WithStablePlaceholders("id")},
+				},
+			},
+		},
+		{
+			name: "test fails when the type of a field skipped with KeepType changes",
+			given: given{
+				args: args{
+					want:    "testdata/assert_json_failure/skips_field_keep_type.json",
+					got:     map[string]any{"id": "4711"},
+					options: []Option{WithSkippedFields(KeepType("id"))},
+				},
+			},
+		},
+		{
+			name: "test fails when comparing semantically and values are different",
+			given: given{
+				args: args{
+					want:    "testdata/assert_json_failure/semantic_compare_different.jsonc",
+					got:     map[string]any{"name": "John", "age": 30},
+					options: []Option{WithSemanticCompare()},
+				},
+			},
+		},
+		{
+			name: "test fails when RawJSON is not valid JSON",
+			given: given{
+				args: args{
+					want: "testdata/assert_json_failure/empty.json",
+					got:  RawJSON(`{"name": "John",}`),
+				},
+			},
+		},
+		{
+			name: "test fails when the type of a gRPC status error's details cannot be resolved",
+			given: given{
+				args: args{
+					want:    "testdata/assert_json_failure/empty.json",
+					got:     newStatusErrorWithDetails(),
+					options: []Option{WithProtoResolver(&protoregistry.Types{})},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -184,6 +278,41 @@ func TestAssertJSON_Failure(t *testing.T) {
 	}
 }
 
+// newProtoMethod returns a protobuf message with fields whose proto and JSON names differ.
+func newProtoMethod() *apipb.Method {
+	return &apipb.Method{
+		Name:              "GetPerson",
+		RequestTypeUrl:    "type.googleapis.com/person.GetPersonRequest",
+		ResponseTypeUrl:   "type.googleapis.com/person.Person",
+		ResponseStreaming: true,
+	}
+}
+
+// newStatusErrorWithDetails returns a gRPC status error with standard error details.
+func newStatusErrorWithDetails() error {
+	st, err := status.New(codes.InvalidArgument, "invalid person").WithDetails(
+		&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: "person.age", Description: "must be positive"},
+		}},
+		&errdetails.ErrorInfo{Reason: "INVALID_AGE", Domain: "person.example.com", Metadata: map[string]string{"age": "-1"}},
+	)
+	if err != nil {
+		panic(err)
+	}
+	return st.Err()
+}
+
+// newErrorDetailsResolver returns a resolver that only knows the error details of newStatusErrorWithDetails.
+func newErrorDetailsResolver() ProtoResolver {
+	var types protoregistry.Types
+	for _, msg := range []proto.Message{&errdetails.BadRequest{}, &errdetails.ErrorInfo{}} {
+		if err := types.RegisterMessage(msg.ProtoReflect().Type()); err != nil {
+			panic(err)
+		}
+	}
+	return &types
+}
+
 func TestAssertJSON(t *testing.T) {
 	type args struct {
 		t       *testing.T
@@ -466,6 +595,26 @@ func TestAssertJSON(t *testing.T) {
 				}(),
 			},
 		},
+		{
+			name: "skips fields when KeepType",
+			given: given{
+				args: args{
+					want: "testdata/assert_json/skips_fields_keep_type.json",
+					got: map[string]any{
+						"id":       4711,
+						"name":     "John",
+						"admin":    false,
+						"address":  map[string]any{"city": "Stockholm"},
+						"roles":    []string{"reader"},
+						"nickname": nil,
+					},
+					options: []Option{
+						WithSkippedFields(KeepType("id"), KeepType("name"), KeepType("admin"), KeepType("address"),
+							KeepType("roles"), KeepType("nickname")),
+					},
+				},
+			},
+		},
 		{
 			name: "skips multiple fields",
 			given: given{
@@ -532,6 +681,25 @@ func TestAssertJSON(t *testing.T) {
 				}(),
 			},
 		},
+		{
+			name: "replaces fields with stable placeholders",
+			given: given{
+				args: args{
+					want: "testdata/assert_json/stable_placeholders.json",
+					got: map[string]any{
+						"customer": map[string]any{"id": "7f3c", "referrerId": nil},
+						"orders": []any{
+							map[string]any{"id": "a91e", "customerId": "7f3c"},
+							map[string]any{"id": "c5d0", "customerId": "7f3c"},
+						},
+					},
+					options: []Option{
+						WithStablePlaceholders("customer.id", "customer.referrerId", "orders.#.id"),
+						WithStablePlaceholders("orders.#.customerId"),
+					},
+				},
+			},
+		},
 		{
 			name: "adds field comments",
 			given: given{
@@ -569,6 +737,23 @@ func TestAssertJSON(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "ignores formatting, key order and comments when comparing semantically",
+			given: given{
+				args: args{
+					want: "testdata/assert_json/semantic_compare_hand_formatted.jsonc",
+					got: map[string]any{
+						"name": "John",
+						"age":  30,
+						"colour": map[string]any{
+							"hair": "black",
+							"eyes": "brown",
+						},
+					},
+					options: []Option{WithSemanticCompare()},
+				},
+			},
+		},
 		{
 			name: "marshals gRPC status error",
 			given: given{
@@ -578,6 +763,142 @@ func TestAssertJSON(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "marshals gRPC status error with details unpacked from Any values",
+			given: given{
+				args: args{
+					want: "testdata/assert_json/marshals_grpc_status_error_details.json",
+					got:  newStatusErrorWithDetails(),
+				},
+			},
+		},
+		{
+			name: "marshals gRPC status error with details resolved by custom resolver",
+			given: given{
+				args: args{
+					want:    "testdata/assert_json/marshals_grpc_status_error_details.json",
+					got:     newStatusErrorWithDetails(),
+					options: []Option{WithProtoResolver(newErrorDetailsResolver())},
+				},
+			},
+		},
+		{
+			name: "indents raw JSON in a byte slice without re-encoding it",
+			given: given{
+				args: args{
+					want: "testdata/assert_json/raw_json.json",
+					got:  []byte(`{"name":"John","age":30,"tags":["a","b"]}`),
+				},
+			},
+		},
+		{
+			name: "indents raw JSON in a string without re-encoding it",
+			given: given{
+				args: args{
+					want: "testdata/assert_json/raw_json.json",
+					got:  `{"name": "John", "age": 30, "tags": ["a", "b"]}`,
+				},
+			},
+		},
+		{
+			name: "indents json.RawMessage",
+			given: given{
+				args: args{
+					want: "testdata/assert_json/raw_json.json",
+					got:  json.RawMessage(`{"name":"John","age":30,"tags":["a","b"]}`),
+				},
+			},
+		},
+		{
+			name: "indents raw JSON read from io.Reader",
+			given: given{
+				args: args{
+					want: "testdata/assert_json/raw_json.json",
+					got:  strings.NewReader("{\n  \"name\": \"John\",\n  \"age\": 30,\n  \"tags\": [\"a\", \"b\"]\n}\n"),
+				},
+			},
+		},
+		{
+			name: "indents RawJSON holding a JSON string",
+			given: given{
+				args: args{
+					want: "testdata/assert_json/raw_json_string.json",
+					got:  RawJSON(`"John"`),
+				},
+			},
+		},
+		{
+			name: "compares empty json.RawMessage as null",
+			given: given{
+				args: args{
+					want: "testdata/assert_json/raw_json_empty.json",
+					got:  json.RawMessage{},
+				},
+			},
+		},
+		{
+			name: "compares nil RawJSON as null",
+			given: given{
+				args: args{
+					want: "testdata/assert_json/raw_json_empty.json",
+					got:  RawJSON(nil),
+				},
+			},
+		},
+		{
+			name: "marshals byte slice that is not JSON as base64",
+			given: given{
+				args: args{
+					want: "testdata/assert_json/marshals_bytes_as_base64.json",
+					got:  []byte("not JSON"),
+				},
+			},
+		},
+		{
+			name: "marshals protobuf message with protojson",
+			given: given{
+				args: args{
+					want: "testdata/assert_json/marshals_proto_message.json",
+					got:  newProtoMethod(),
+				},
+			},
+		},
+		{
+			name: "marshals protobuf message with proto field names",
+			given: given{
+				args: args{
+					want:    "testdata/assert_json/marshals_proto_message_proto_names.json",
+					got:     newProtoMethod(),
+					options: []Option{WithProtoNames()},
+				},
+			},
+		},
+		{
+			name: "marshals protobuf message with unpopulated fields",
+			given: given{
+				args: args{
+					want:    "testdata/assert_json/marshals_proto_message_emit_unpopulated.json",
+					got:     newProtoMethod(),
+					options: []Option{WithEmitUnpopulated()},
+				},
+			},
+		},
+		{
+			name: "marshals protobuf well-known types in their canonical JSON form",
+			given: given{
+				args: args{
+					want: "testdata/assert_json/marshals_proto_well_known_types.json",
+					got: &structpb.Struct{Fields: map[string]*structpb.Value{
+						"name": structpb.NewStringValue("John"),
+						"age":  structpb.NewNumberValue(30),
+						"tags": structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{
+							structpb.NewStringValue("a"),
+							structpb.NewNullValue(),
+						}}),
+					}},
+				},
+			},
+		},
 		{
 			name: "skips fields four levels deep in nested arrays using # character",
 			given: given{
@@ -676,7 +997,6 @@ func TestCheckNotZeroTime(t *testing.T) {
 	type given struct {
 		args args
 		json string
-		t    *testing.T // should not be initialized in the test cases
 	}
 	type want struct {
 		failed bool // true if the test should fail, false if it should pass
@@ -747,16 +1067,15 @@ func TestCheckNotZeroTime(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			/* ---------------------------------- Given --------------------------------- */
 			require := require.New(t)
-			tt.given.t = &testing.T{} // test result recorder
 			fileBytes := readFile(t, tt.given.json)
-			g := &golden{result: fileBytes}
+			doc := &Document{result: fileBytes}
 
 			/* ---------------------------------- When ---------------------------------- */
-			CheckNotZeroTime(tt.given.args.path, tt.given.args.layout).Apply(tt.given.t, false, g, "")
+			err := CheckNotZeroTime(tt.given.args.path, tt.given.args.layout).Apply(doc, "")
 
 			/* ---------------------------------- Then ---------------------------------- */
-			// Assert the test result
-			require.Equal(tt.want.failed, tt.given.t.Failed())
+			// Assert the option result
+			require.Equal(tt.want.failed, err != nil, "error: %v", err)
 		})
 	}
 }
@@ -770,7 +1089,6 @@ func TestCheckEqualTimes(t *testing.T) {
 	type given struct {
 		args args
 		json string
-		t    *testing.T // should not be initialized in the test cases
 	}
 	type want struct {
 		failed bool // true if the test should fail, false if it should pass
@@ -870,14 +1188,521 @@ func TestCheckEqualTimes(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			/* ---------------------------------- Given --------------------------------- */
 			require := require.New(t)
-			tt.given.t = &testing.T{} // test result recorder
 			fileBytes := readFile(t, tt.given.json)
-			g := &golden{result: fileBytes}
+			doc := &Document{result: fileBytes}
+
+			/* ---------------------------------- When ---------------------------------- */
+			err := CheckEqualTimes(tt.given.args.a, tt.given.args.b, tt.given.args.layout).Apply(doc, "")
+
+			/* ---------------------------------- Then ---------------------------------- */
+			// Assert the option result
+			require.Equal(tt.want.failed, err != nil, "error: %v", err)
+		})
+	}
+}
+
+func TestAssertJSON_WriteActualFiles(t *testing.T) {
+	type args struct {
+		t       *testing.T
+		want    string
+		got     any
+		options []Option
+	}
+	type given struct {
+		args args
+		// staleActual is true when a stale ".actual" file exists before the comparison
+		staleActual bool
+		// artifactsDir is true when the ".actual" file should be written to an artifacts directory
+		artifactsDir bool
+	}
+	type want struct {
+		// actual is the expected content of the ".actual" file, or empty if it should not exist
+		actual string
+		// failure is true when the test case should fail
+		failure bool
+	}
+	type test struct {
+		name  string
+		given given
+		want  want
+	}
+	tests := []test{
+		{
+			name: "writes actual file next to golden file when comparison fails",
+			given: given{
+				args: args{
+					want: "testdata/assert_json_actual_file/fails.json",
+					got:  map[string]any{"name": "John", "age": 30},
+				},
+			},
+			want: want{
+				actual: `{
+    "age": 30,
+    "name": "John"
+}`,
+				failure: true,
+			},
+		},
+		{
+			name: "writes actual file to artifacts directory when comparison fails",
+			given: given{
+				args: args{
+					want: "testdata/assert_json_actual_file/fails.json",
+					got:  map[string]any{"name": "John", "age": 30},
+				},
+				artifactsDir: true,
+			},
+			want: want{
+				actual: `{
+    "age": 30,
+    "name": "John"
+}`,
+				failure: true,
+			},
+		},
+		{
+			name: "removes stale actual file when comparison passes",
+			given: given{
+				args: args{
+					want: "testdata/assert_json_actual_file/passes.json",
+					got:  map[string]any{"name": "John", "age": 30},
+				},
+				staleActual: true,
+			},
+			want: want{failure: false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- Given --------------------------------- */
+			var dir string
+			if tt.given.artifactsDir {
+				dir = t.TempDir()
+			}
+			tt.given.args.options = append(tt.given.args.options, WriteActualFiles(dir))
+			actualPath := actualFilePath(&Document{writeActual: true, actualDir: dir}, tt.given.args.want)
+			defer os.Remove(actualPath)
+			if tt.given.staleActual {
+				writeFile(t, actualPath, []byte("stale"))
+			}
+
+			tt.given.args.t = &testing.T{} // test result recorder
 
 			/* ---------------------------------- When ---------------------------------- */
-			CheckEqualTimes(tt.given.args.a, tt.given.args.b, tt.given.args.layout).Apply(tt.given.t, false, g, "") /* ---------------------------------- Then ---------------------------------- */
-			// Assert the test result
-			require.Equal(tt.want.failed, tt.given.t.Failed())
+			AssertJSON(tt.given.args.t, tt.given.args.want, tt.given.args.got, tt.given.args.options...)
+
+			/* ---------------------------------- Then ---------------------------------- */
+			require.Equal(t, tt.want.failure, tt.given.args.t.Failed())
+			if tt.want.actual == "" {
+				require.NoFileExists(t, actualPath)
+				return
+			}
+			if tt.given.artifactsDir {
+				require.Equal(t, filepath.Join(dir, tt.given.args.want+".actual"), actualPath)
+			}
+			require.Equal(t, tt.want.actual, string(readFile(t, actualPath)))
+		})
+	}
+}
+
+func TestAssertJSON_CreateMissingGoldenFiles(t *testing.T) {
+	type args struct {
+		t       *testing.T
+		want    string
+		got     any
+		options []Option
+	}
+	type given struct {
+		args args
+		// existing is the content of the golden file before the comparison, or empty if it does not exist
+		existing string
+	}
+	type want struct {
+		// json is the expected JSON content of the golden file after the comparison
+		json string
+	}
+	type test struct {
+		name  string
+		given given
+		want  want
+	}
+	tests := []test{
+		{
+			name: "creates missing golden file and its parent directories",
+			given: given{
+				args: args{
+					want: "sub/dir/created.json",
+					got:  map[string]any{"name": "John", "age": 30},
+				},
+			},
+			want: want{
+				json: `{
+    "age": 30,
+    "name": "John"
+}`,
+			},
+		},
+		{
+			name: "does not touch existing golden file",
+			given: given{
+				args: args{
+					want: "existing.json",
+					got:  map[string]any{"name": "John", "age": 30},
+				},
+				existing: `{
+    "age": 31,
+    "name": "John"
+}`,
+			},
+			want: want{
+				json: `{
+    "age": 31,
+    "name": "John"
+}`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- Given --------------------------------- */
+			tt.given.args.want = filepath.Join(t.TempDir(), tt.given.args.want)
+			if tt.given.existing != "" {
+				writeFile(t, tt.given.args.want, []byte(tt.given.existing))
+			}
+			tt.given.args.options = append(tt.given.args.options, CreateMissingGoldenFiles())
+
+			tt.given.args.t = &testing.T{} // test result recorder
+
+			/* ---------------------------------- When ---------------------------------- */
+			AssertJSON(tt.given.args.t, tt.given.args.want, tt.given.args.got, tt.given.args.options...)
+
+			/* ---------------------------------- Then ---------------------------------- */
+			// The test fails both when the golden file is created, and when it differs from the existing one
+			require.True(t, tt.given.args.t.Failed(), "want test failed got passed")
+			require.Equal(t, tt.want.json, string(readFile(t, tt.given.args.want)))
+		})
+	}
+}
+
+func TestShouldUpdateGoldenFile(t *testing.T) {
+	type given struct {
+		// env are the environment variables set for the test case
+		env  map[string]string
+		path string
+	}
+	type want struct {
+		update bool
+		// failure is true when the filters are invalid and the test should fail
+		failure bool
+	}
+	type test struct {
+		name  string
+		given given
+		want  want
+	}
+	tests := []test{
+		{
+			name: "no update when UPDATE_GOLDENS is not set",
+			given: given{
+				env:  map[string]string{"UPDATE_GOLDENS": ""},
+				path: "testdata/get_person/happy_path.json",
+			},
+			want: want{update: false},
+		},
+		{
+			name: "updates when UPDATE_GOLDENS is set without filters",
+			given: given{
+				env:  map[string]string{"UPDATE_GOLDENS": "1"},
+				path: "testdata/get_person/happy_path.json",
+			},
+			want: want{update: true},
+		},
+		{
+			name: "updates when test name matches",
+			given: given{
+				env:  map[string]string{"UPDATE_GOLDENS": "1", "UPDATE_GOLDENS_MATCH": "test_name_matches$"},
+				path: "testdata/get_person/happy_path.json",
+			},
+			want: want{update: true},
+		},
+		{
+			name: "no update when test name does not match",
+			given: given{
+				env:  map[string]string{"UPDATE_GOLDENS": "1", "UPDATE_GOLDENS_MATCH": "^TestGetPerson"},
+				path: "testdata/get_person/happy_path.json",
+			},
+			want: want{update: false},
+		},
+		{
+			name: "updates when path matches glob",
+			given: given{
+				env:  map[string]string{"UPDATE_GOLDENS": "1", "UPDATE_GOLDENS_PATH": "testdata/get_person/*.json"},
+				path: "testdata/get_person/happy_path.json",
+			},
+			want: want{update: true},
+		},
+		{
+			name: "updates when path matches glob with double star",
+			given: given{
+				env:  map[string]string{"UPDATE_GOLDENS": "1", "UPDATE_GOLDENS_PATH": "**/happy_*.json"},
+				path: "testdata/get_person/happy_path.json",
+			},
+			want: want{update: true},
+		},
+		{
+			name: "updates when path matches glob with an escaped character class",
+			given: given{
+				env:  map[string]string{"UPDATE_GOLDENS": "1", "UPDATE_GOLDENS_PATH": `testdata/**/[\]h]appy_path.json`},
+				path: "testdata/get_person/happy_path.json",
+			},
+			want: want{update: true},
+		},
+		{
+			name: "no update when path does not match glob",
+			given: given{
+				env:  map[string]string{"UPDATE_GOLDENS": "1", "UPDATE_GOLDENS_PATH": "testdata/*.json"},
+				path: "testdata/get_person/happy_path.json",
+			},
+			want: want{update: false},
+		},
+		{
+			name: "no update when both filters are set and only the path matches",
+			given: given{
+				env: map[string]string{
+					"UPDATE_GOLDENS":       "1",
+					"UPDATE_GOLDENS_MATCH": "^TestGetPerson",
+					"UPDATE_GOLDENS_PATH":  "testdata/get_person/*.json",
+				},
+				path: "testdata/get_person/happy_path.json",
+			},
+			want: want{update: false},
+		},
+		{
+			name: "fails when the test name filter is invalid",
+			given: given{
+				env:  map[string]string{"UPDATE_GOLDENS": "1", "UPDATE_GOLDENS_MATCH": "("},
+				path: "testdata/get_person/happy_path.json",
+			},
+			want: want{update: false, failure: true},
+		},
+		{
+			name: "fails when the path filter is invalid",
+			given: given{
+				env:  map[string]string{"UPDATE_GOLDENS": "1", "UPDATE_GOLDENS_PATH": "testdata/["},
+				path: "testdata/get_person/happy_path.json",
+			},
+			want: want{update: false, failure: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- Given --------------------------------- */
+			for k, v := range tt.given.env {
+				t.Setenv(k, v)
+			}
+			tb := t
+			if tt.want.failure {
+				tb = &testing.T{} // test result recorder
+			}
+
+			/* ---------------------------------- When ---------------------------------- */
+			got := shouldUpdateGoldenFile(tb, tt.given.path)
+
+			/* ---------------------------------- Then ---------------------------------- */
+			require.Equal(t, tt.want.update, got)
+			require.Equal(t, tt.want.failure, tb.Failed())
+		})
+	}
+}
+
+func TestRequireJSON(t *testing.T) {
+	type args struct {
+		want    string
+		got     any
+		options []Option
+	}
+	type given struct {
+		args args
+	}
+	type want struct {
+		// failure is true when the test case should fail
+		failure bool
+		// stopped is true when execution should be stopped
+		stopped bool
+	}
+	type test struct {
+		name  string
+		given given
+		want  want
+	}
+	tests := []test{
+		{
+			name: "continues execution when the golden file's content is equal to the got JSON",
+			given: given{
+				args: args{
+					want: "testdata/assert_json/same_content.json",
+					got:  map[string]any{"name": "John", "age": 30},
+				},
+			},
+			want: want{failure: false, stopped: false},
+		},
+		{
+			name: "stops execution when the golden file's content is different from the got JSON",
+			given: given{
+				args: args{
+					want: "testdata/assert_json_failure/json_different.json",
+					got:  map[string]any{"name": "John", "age": 30},
+				},
+			},
+			want: want{failure: true, stopped: true},
+		},
+		{
+			name: "stops execution when an option fails",
+			given: given{
+				args: args{
+					want:    "testdata/assert_json/same_content.json",
+					got:     map[string]any{"name": "John", "age": 30},
+					options: []Option{WithSkippedFields("colour")},
+				},
+			},
+			want: want{failure: true, stopped: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- Given --------------------------------- */
+			tb := newFakeTB(t.Name()) // test result recorder
+			var returned bool
+
+			/* ---------------------------------- When ---------------------------------- */
+			tb.run(func(tb testing.TB) {
+				RequireJSON(tb, tt.given.args.want, tt.given.args.got, tt.given.args.options...)
+				returned = true
+			})
+
+			/* ---------------------------------- Then ---------------------------------- */
+			require.Equal(t, tt.want.failure, tb.Failed())
+			require.Equal(t, tt.want.stopped, tb.stopped)
+			require.Equal(t, !tt.want.stopped, returned)
+		})
+	}
+}
+
+func BenchmarkAssertJSON(b *testing.B) {
+	got := map[string]any{"name": "John", "age": 30}
+	for i := 0; i < b.N; i++ {
+		AssertJSON(b, "testdata/assert_json/same_content.json", got)
+	}
+}
+
+func TestAssertJSONAuto(t *testing.T) {
+	type args struct {
+		got     any
+		options []Option
+	}
+	type given struct {
+		args args
+	}
+	type test struct {
+		name  string
+		given given
+	}
+	tests := []test{
+		{
+			name: "derives path from test name",
+			given: given{
+				args: args{
+					got: map[string]any{"name": "John", "age": 30},
+				},
+			},
+		},
+		{
+			name: "derives .jsonc extension from comment options",
+			given: given{
+				args: args{
+					got:     map[string]any{"name": "John", "age": 30},
+					options: []Option{WithFieldComments([]FieldComment{{Path: "age", Comment: "Should be 30"}})},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- Given --------------------------------- */
+			tb := newFakeTB(t.Name()) // test result recorder
+
+			/* ---------------------------------- When ---------------------------------- */
+			AssertJSONAuto(tb, tt.given.args.got, tt.given.args.options...)
+
+			/* ---------------------------------- Then ---------------------------------- */
+			require.False(t, tb.Failed(), "want test passed got failed: %v", tb.errors)
+		})
+	}
+}
+
+func TestPath(t *testing.T) {
+	type args struct {
+		options []Option
+	}
+	type given struct {
+		args args
+		// testName is the name of the test the path is derived from
+		testName string
+	}
+	type want struct {
+		path string
+	}
+	type test struct {
+		name  string
+		given given
+		want  want
+	}
+	tests := []test{
+		{
+			name: "uses test name in testdata directory",
+			given: given{
+				testName: "TestGetPerson",
+			},
+			want: want{path: filepath.Join("testdata", "TestGetPerson.json")},
+		},
+		{
+			name: "uses subtest names as directories",
+			given: given{
+				testName: "TestGetPerson/happy_path/nested",
+			},
+			want: want{path: filepath.Join("testdata", "TestGetPerson", "happy_path", "nested.json")},
+		},
+		{
+			name: "sanitizes unsafe characters",
+			given: given{
+				testName: `TestGetPerson/a:b*c?d"e<f>g|h\i#01/..`,
+			},
+			want: want{path: filepath.Join("testdata", "TestGetPerson", "a_b_c_d_e_f_g_h_i_01", "___.json")},
+		},
+		{
+			name: "uses golden directory option",
+			given: given{
+				args:     args{options: []Option{WithGoldenDir(filepath.Join("testdata", "goldens"))}},
+				testName: "TestGetPerson",
+			},
+			want: want{path: filepath.Join("testdata", "goldens", "TestGetPerson.json")},
+		},
+		{
+			name: "uses .jsonc extension with file comment",
+			given: given{
+				args:     args{options: []Option{WithFileComment("comment")}},
+				testName: "TestGetPerson",
+			},
+			want: want{path: filepath.Join("testdata", "TestGetPerson.jsonc")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- When ---------------------------------- */
+			got := Path(newFakeTB(tt.given.testName), tt.given.args.options...)
+
+			/* ---------------------------------- Then ---------------------------------- */
+			require.Equal(t, tt.want.path, got)
 		})
 	}
 }
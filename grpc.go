@@ -0,0 +1,408 @@
+package golden
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// GRPCRecorder records gRPC calls, i.e. their request and response messages, metadata and status, so that whole
+// conversations can be compared with golden files with AssertGRPCCalls. It provides client and server interceptors
+// for both unary and streaming calls, and is safe for concurrent use.
+//
+// Use the client interceptors to record the calls a client makes, and the server interceptors to record the calls a
+// server handles. In tests, connect them with google.golang.org/grpc/test/bufconn, so no network is needed.
+//
+// Example:
+//
+//	rec := golden.NewGRPCRecorder()
+//	conn, err := grpc.NewClient("passthrough:///bufnet",
+//	    grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+//	    grpc.WithTransportCredentials(insecure.NewCredentials()),
+//	    grpc.WithUnaryInterceptor(rec.UnaryClientInterceptor()),
+//	    grpc.WithStreamInterceptor(rec.StreamClientInterceptor()),
+//	)
+type GRPCRecorder struct {
+	mu    sync.Mutex
+	calls []*grpcCall
+}
+
+// grpcCall is a recorded gRPC call. The messages are clones, since the caller may reuse them.
+type grpcCall struct {
+	method    string
+	metadata  metadata.MD
+	requests  []proto.Message
+	header    metadata.MD
+	responses []proto.Message
+	trailer   metadata.MD
+	// status is nil until the call has finished.
+	status *status.Status
+}
+
+// NewGRPCRecorder returns a GRPCRecorder without any recorded calls.
+func NewGRPCRecorder() *GRPCRecorder {
+	return &GRPCRecorder{}
+}
+
+// start records the start of a call of the method with the metadata.
+func (r *GRPCRecorder) start(method string, md metadata.MD) *grpcCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	call := &grpcCall{method: method, metadata: md.Copy()}
+	r.calls = append(r.calls, call)
+	return call
+}
+
+// addRequest records a request message of the call.
+func (r *GRPCRecorder) addRequest(call *grpcCall, m any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if msg, ok := m.(proto.Message); ok {
+		call.requests = append(call.requests, proto.Clone(msg))
+	}
+}
+
+// addResponse records a response message of the call.
+func (r *GRPCRecorder) addResponse(call *grpcCall, m any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if msg, ok := m.(proto.Message); ok {
+		call.responses = append(call.responses, proto.Clone(msg))
+	}
+}
+
+// finish records the status of the call, and its header and trailer metadata, unless it has already finished.
+func (r *GRPCRecorder) finish(call *grpcCall, err error, header, trailer metadata.MD) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if call.status != nil {
+		return
+	}
+	if call.status = status.Convert(err); call.status == nil {
+		call.status = status.New(codes.OK, "")
+	}
+	call.header, call.trailer = header.Copy(), trailer.Copy()
+}
+
+// UnaryClientInterceptor returns an interceptor that records the unary calls made by a client.
+func (r *GRPCRecorder) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		call := r.start(method, md)
+		r.addRequest(call, req)
+
+		var header, trailer metadata.MD
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Header(&header), grpc.Trailer(&trailer))...)
+		if err == nil {
+			r.addResponse(call, reply)
+		}
+		r.finish(call, err, header, trailer)
+		return err
+	}
+}
+
+// StreamClientInterceptor returns an interceptor that records the streaming calls made by a client.
+func (r *GRPCRecorder) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		call := r.start(method, md)
+
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			r.finish(call, err, nil, nil)
+			return nil, err
+		}
+		return &recordingClientStream{ClientStream: stream, recorder: r, call: call, desc: desc}, nil
+	}
+}
+
+// recordingClientStream records the messages sent and received on a client stream.
+type recordingClientStream struct {
+	grpc.ClientStream
+	recorder *GRPCRecorder
+	call     *grpcCall
+	desc     *grpc.StreamDesc
+}
+
+func (s *recordingClientStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.recorder.addRequest(s.call, m)
+	}
+	return err
+}
+
+func (s *recordingClientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == nil:
+		s.recorder.addResponse(s.call, m)
+		// A call without server streaming ends with its only response.
+		if !s.desc.ServerStreams {
+			s.recordFinish(nil)
+		}
+	case errors.Is(err, io.EOF):
+		s.recordFinish(nil)
+	default:
+		s.recordFinish(err)
+	}
+	return err
+}
+
+// recordFinish records the status of the call. The header and trailer are available once the call has finished.
+func (s *recordingClientStream) recordFinish(err error) {
+	header, _ := s.ClientStream.Header()
+	s.recorder.finish(s.call, err, header, s.ClientStream.Trailer())
+}
+
+// UnaryServerInterceptor returns an interceptor that records the unary calls handled by a server.
+func (r *GRPCRecorder) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		call := r.start(info.FullMethod, md)
+		r.addRequest(call, req)
+
+		resp, err := handler(ctx, req)
+		if err == nil {
+			r.addResponse(call, resp)
+		}
+		r.finish(call, err, nil, nil)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns an interceptor that records the streaming calls handled by a server.
+func (r *GRPCRecorder) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		md, _ := metadata.FromIncomingContext(ss.Context())
+		call := r.start(info.FullMethod, md)
+
+		err := handler(srv, &recordingServerStream{ServerStream: ss, recorder: r, call: call})
+		r.finish(call, err, nil, nil)
+		return err
+	}
+}
+
+// recordingServerStream records the messages received and sent on a server stream.
+type recordingServerStream struct {
+	grpc.ServerStream
+	recorder *GRPCRecorder
+	call     *grpcCall
+}
+
+func (s *recordingServerStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.recorder.addRequest(s.call, m)
+	}
+	return err
+}
+
+func (s *recordingServerStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.recorder.addResponse(s.call, m)
+	}
+	return err
+}
+
+// AssertGRPCCalls compares snapshots of all calls recorded by rec, in the order they were started, with the JSON
+// golden file at want. If they are not equal, the test is marked as failed, but execution continues.
+//
+// The golden file is an array with a snapshot per call:
+//
+//	[
+//	    {
+//	        "method": "/person.PersonService/GetPerson",
+//	        "metadata": {
+//	            "authorization": "--* SKIPPED *--"
+//	        },
+//	        "requests": [
+//	            {
+//	                "id": "1"
+//	            }
+//	        ],
+//	        "responses": [],
+//	        "status": {
+//	            "code": "NotFound",
+//	            "message": "person not found"
+//	        }
+//	    }
+//	]
+//
+// Messages are marshalled with protojson, see WithProtoNames and WithEmitUnpopulated, and the details of the status
+// are unpacked, see WithProtoResolver. The metadata is that of the request, and "header" and "trailer" hold the
+// metadata of the response, which are only recorded by the client interceptors. Metadata keys added by the gRPC
+// transport, e.g. ":authority", "content-type", "user-agent" and those starting with "grpc-", are left out, and the
+// value of "authorization" is skipped by default. Use WithSkippedMetadata to skip other keys, and WithSkippedFields on
+// the array, e.g. WithSkippedFields("0.responses.0.createTime"). A call that has not finished has a null status.
+func AssertGRPCCalls(t testing.TB, want string, rec *GRPCRecorder, opts ...Option) {
+	t.Helper()
+	compareGRPCCalls(t, false, want, rec, opts...)
+}
+
+// RequireGRPCCalls is like AssertGRPCCalls, but if the golden file and the snapshots of the calls are not equal, the
+// test is marked as failed and execution stops.
+func RequireGRPCCalls(t testing.TB, want string, rec *GRPCRecorder, opts ...Option) {
+	t.Helper()
+	compareGRPCCalls(t, true, want, rec, opts...)
+}
+
+func compareGRPCCalls(t testing.TB, failNow bool, want string, rec *GRPCRecorder, opts ...Option) {
+	t.Helper()
+
+	snapshots, err := rec.snapshot(opts)
+	if !NoError(t, failNow, err, "taking snapshot of gRPC calls") {
+		return
+	}
+	data, err := marshalSnapshot(snapshots)
+	if !NoError(t, failNow, err, "marshalling gRPC calls") {
+		return
+	}
+
+	compareJSON(t, failNow, want, data, append(opts, envOptions(t, want)...)...)
+}
+
+// WithSkippedMetadata replaces the values of the metadata keys with "--* SKIPPED *--" in the snapshots of gRPC calls.
+// This is useful for metadata that changes on every call, e.g. request IDs, and for secrets. The keys are matched
+// case-insensitively, and keys that are missing are ignored.
+//
+// NOTE! This option only affects AssertGRPCCalls and RequireGRPCCalls.
+func WithSkippedMetadata(keys ...string) Option {
+	return grpcOption{configure: func(skipped map[string]bool) {
+		for _, key := range keys {
+			skipped[strings.ToLower(key)] = true
+		}
+	}}
+}
+
+// grpcOption implements Option for configuring the snapshots of gRPC calls. It is read before the snapshots are
+// taken, so applying it to the document does nothing.
+type grpcOption struct {
+	configure func(skipped map[string]bool)
+}
+
+func (g grpcOption) Apply(*Document, string) error {
+	return nil
+}
+
+func (g grpcOption) IsType() OptionType {
+	return OptionTypeConfig
+}
+
+// grpcCallSnapshot is the JSON document a gRPC call is compared as.
+type grpcCallSnapshot struct {
+	Method    string              `json:"method"`
+	Metadata  map[string]any      `json:"metadata,omitempty"`
+	Requests  []json.RawMessage   `json:"requests"`
+	Header    map[string]any      `json:"header,omitempty"`
+	Responses []json.RawMessage   `json:"responses"`
+	Trailer   map[string]any      `json:"trailer,omitempty"`
+	Status    *grpcStatusSnapshot `json:"status"`
+}
+
+// grpcStatusSnapshot is the JSON document the status of a gRPC call is compared as.
+type grpcStatusSnapshot struct {
+	// Code is the name of the status code, e.g. "NotFound".
+	Code    string            `json:"code"`
+	Message string            `json:"message,omitempty"`
+	Details []json.RawMessage `json:"details,omitempty"`
+}
+
+// snapshot returns the snapshots of the recorded calls, configured by the options.
+func (r *GRPCRecorder) snapshot(opts []Option) ([]grpcCallSnapshot, error) {
+	skipped := map[string]bool{"authorization": true}
+	for _, opt := range opts {
+		if o, ok := opt.(grpcOption); ok {
+			o.configure(skipped)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	snapshots := make([]grpcCallSnapshot, 0, len(r.calls))
+	for _, call := range r.calls {
+		snapshot := grpcCallSnapshot{
+			Method:    call.method,
+			Metadata:  newMetadataSnapshot(call.metadata, skipped),
+			Requests:  []json.RawMessage{},
+			Header:    newMetadataSnapshot(call.header, skipped),
+			Responses: []json.RawMessage{},
+			Trailer:   newMetadataSnapshot(call.trailer, skipped),
+		}
+		for _, msg := range call.requests {
+			data, err := marshalProto(msg, opts)
+			if err != nil {
+				return nil, err
+			}
+			snapshot.Requests = append(snapshot.Requests, data)
+		}
+		for _, msg := range call.responses {
+			data, err := marshalProto(msg, opts)
+			if err != nil {
+				return nil, err
+			}
+			snapshot.Responses = append(snapshot.Responses, data)
+		}
+		if call.status != nil {
+			st := &grpcStatusSnapshot{Code: call.status.Code().String(), Message: call.status.Message()}
+			for _, detail := range call.status.Proto().GetDetails() {
+				data, err := marshalProto(detail, opts)
+				if err != nil {
+					return nil, err
+				}
+				st.Details = append(st.Details, data)
+			}
+			snapshot.Status = st
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+// newMetadataSnapshot returns the metadata without the keys added by the gRPC transport, and with the values of
+// skipped keys replaced. Keys with a single value are strings, and keys with several values are arrays.
+func newMetadataSnapshot(md metadata.MD, skipped map[string]bool) map[string]any {
+	snapshot := make(map[string]any)
+	keys := make([]string, 0, len(md))
+	for key := range md {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		values := md[key]
+		switch {
+		case isTransportMetadata(key):
+			continue
+		case skipped[key]:
+			snapshot[key] = "--* SKIPPED *--"
+		case len(values) == 1:
+			snapshot[key] = values[0]
+		default:
+			snapshot[key] = values
+		}
+	}
+	return snapshot
+}
+
+// isTransportMetadata reports whether the metadata key is added by the gRPC transport, rather than by the
+// application, e.g. the pseudo-header ":authority" or the user agent, whose version changes with gRPC upgrades.
+func isTransportMetadata(key string) bool {
+	return strings.HasPrefix(key, ":") || strings.HasPrefix(key, "grpc-") || key == "content-type" ||
+		key == "user-agent" || key == "te"
+}
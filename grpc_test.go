@@ -0,0 +1,144 @@
+package golden
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// echoServiceDesc describes a service that echoes strings in upper case, both in unary and bidirectional streaming
+// calls. Upper fails with newStatusErrorWithDetails when the string is empty.
+var echoServiceDesc = grpc.ServiceDesc{
+	ServiceName: "golden.test.Echo",
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Upper",
+		Handler: func(_ any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			req := &wrapperspb.StringValue{}
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req any) (any, error) {
+				if err := grpc.SetHeader(ctx, metadata.Pairs("x-request-id", "r-1")); err != nil {
+					return nil, err
+				}
+				value := req.(*wrapperspb.StringValue).GetValue()
+				if value == "" {
+					return nil, newStatusErrorWithDetails()
+				}
+				return wrapperspb.String(strings.ToUpper(value)), nil
+			}
+			if interceptor == nil {
+				return handler(ctx, req)
+			}
+			return interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: "/golden.test.Echo/Upper"}, handler)
+		},
+	}},
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Chat",
+		ServerStreams: true,
+		ClientStreams: true,
+		Handler: func(_ any, stream grpc.ServerStream) error {
+			stream.SetTrailer(metadata.Pairs("x-messages", "done"))
+			for {
+				req := &wrapperspb.StringValue{}
+				if err := stream.RecvMsg(req); errors.Is(err, io.EOF) {
+					return nil
+				} else if err != nil {
+					return err
+				}
+				if err := stream.SendMsg(wrapperspb.String(strings.ToUpper(req.GetValue()))); err != nil {
+					return err
+				}
+			}
+		},
+	}},
+}
+
+// newEchoConn starts the echo service on an in-memory listener, and returns a client connection to it. The server
+// and client options are used to add the interceptors.
+func newEchoConn(t *testing.T, serverOpts []grpc.ServerOption, clientOpts ...grpc.DialOption) *grpc.ClientConn {
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(serverOpts...)
+	server.RegisterService(&echoServiceDesc, nil)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet", append(clientOpts,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// callEcho calls Upper with "hello" and with an empty string, and then chats "hi" and "bye".
+func callEcho(t *testing.T, conn *grpc.ClientConn) {
+	ctx := metadata.AppendToOutgoingContext(context.Background(),
+		"authorization", "Bearer secret", "x-tenant", "acme", "x-trace", "a", "x-trace", "b")
+
+	reply := &wrapperspb.StringValue{}
+	require.NoError(t, conn.Invoke(ctx, "/golden.test.Echo/Upper", wrapperspb.String("hello"), reply))
+	require.Equal(t, "HELLO", reply.GetValue())
+	require.Error(t, conn.Invoke(ctx, "/golden.test.Echo/Upper", wrapperspb.String(""), reply))
+
+	stream, err := conn.NewStream(ctx, &echoServiceDesc.Streams[0], "/golden.test.Echo/Chat")
+	require.NoError(t, err)
+	for _, s := range []string{"hi", "bye"} {
+		require.NoError(t, stream.SendMsg(wrapperspb.String(s)))
+		require.NoError(t, stream.RecvMsg(reply))
+	}
+	require.NoError(t, stream.CloseSend())
+	require.ErrorIs(t, stream.RecvMsg(reply), io.EOF)
+}
+
+func TestAssertGRPCCalls(t *testing.T) {
+	/* ---------------------------------- Given --------------------------------- */
+	clientRec, serverRec := NewGRPCRecorder(), NewGRPCRecorder()
+	conn := newEchoConn(t,
+		[]grpc.ServerOption{
+			grpc.UnaryInterceptor(serverRec.UnaryServerInterceptor()),
+			grpc.StreamInterceptor(serverRec.StreamServerInterceptor()),
+		},
+		grpc.WithUnaryInterceptor(clientRec.UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(clientRec.StreamClientInterceptor()),
+	)
+	tb := newFakeTB(t.Name()) // test result recorder
+
+	/* ---------------------------------- When ---------------------------------- */
+	callEcho(t, conn)
+	AssertGRPCCalls(tb, "testdata/assert_grpc/client_calls.json", clientRec,
+		WithSkippedMetadata("X-Request-Id"), WithProtoResolver(newErrorDetailsResolver()))
+	AssertGRPCCalls(tb, "testdata/assert_grpc/server_calls.json", serverRec, WithProtoResolver(newErrorDetailsResolver()))
+
+	/* ---------------------------------- Then ---------------------------------- */
+	require.False(t, tb.Failed(), "errors: %v", tb.errors)
+}
+
+func TestAssertGRPCCalls_Failure(t *testing.T) {
+	/* ---------------------------------- Given --------------------------------- */
+	rec := NewGRPCRecorder()
+	conn := newEchoConn(t, nil, grpc.WithUnaryInterceptor(rec.UnaryClientInterceptor()))
+	tb := newFakeTB(t.Name()) // test result recorder
+
+	/* ---------------------------------- When ---------------------------------- */
+	reply := &wrapperspb.StringValue{}
+	require.NoError(t, conn.Invoke(context.Background(), "/golden.test.Echo/Upper", wrapperspb.String("bye"), reply))
+	AssertGRPCCalls(tb, "testdata/assert_grpc/client_calls.json", rec,
+		WithSkippedMetadata("X-Request-Id"), WithProtoResolver(newErrorDetailsResolver()))
+
+	/* ---------------------------------- Then ---------------------------------- */
+	require.True(t, tb.Failed())
+	require.Contains(t, tb.errors[0], `changed      0.requests.0: "hello" => "bye"`)
+}
@@ -1,21 +1,109 @@
 package golden
 
 import (
+	"fmt"
 	"os"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 )
 
-func readFile(t *testing.T, path string) []byte {
+func readFile(t testing.TB, path string) []byte {
 	t.Helper()
 	b, err := os.ReadFile(path)
 	require.NoError(t, err, "failed to read file")
 	return b
 }
 
-func writeFile(t *testing.T, path string, content []byte) {
+func writeFile(t testing.TB, path string, content []byte) {
 	t.Helper()
 	err := os.WriteFile(path, content, 0644)
 	require.NoError(t, err, "failed to write file")
 }
+
+// fakeTB is a testing.TB that records failures instead of reporting them. Like the real implementations, FailNow
+// stops the calling goroutine, hence functions that may call it should be invoked via run.
+type fakeTB struct {
+	testing.TB // panics if methods not implemented by fakeTB are called
+	name       string
+
+	mu      sync.Mutex
+	failed  bool
+	stopped bool
+	errors  []string
+}
+
+// newFakeTB returns a fakeTB with the given test name.
+func newFakeTB(name string) *fakeTB {
+	return &fakeTB{name: name}
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Name() string {
+	return f.name
+}
+
+func (f *fakeTB) Errorf(format string, args ...any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+	f.failed = true
+}
+
+func (f *fakeTB) Logf(string, ...any) {}
+
+func (f *fakeTB) Fail() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failed = true
+}
+
+func (f *fakeTB) FailNow() {
+	f.mu.Lock()
+	f.failed = true
+	f.stopped = true
+	f.mu.Unlock()
+	runtime.Goexit()
+}
+
+func (f *fakeTB) Failed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.failed
+}
+
+// run calls fn in a separate goroutine and waits for it to return or be stopped by FailNow.
+func (f *fakeTB) run(fn func(tb testing.TB)) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(f)
+	}()
+	<-done
+}
+
+// requireFailures requires that the assertion run with tb reported exactly the failures, in order. The failures are
+// the option failures and the differences listed in the failure messages, e.g. "path = items: path not found" and
+// `changed      name: "John" => "Jane"`. Without failures, the assertion must have passed.
+func requireFailures(t *testing.T, tb *fakeTB, failures ...string) {
+	t.Helper()
+	if len(failures) == 0 {
+		require.False(t, tb.Failed(), "errors: %v", tb.errors)
+		return
+	}
+	require.True(t, tb.Failed())
+	var got []string
+	for _, msg := range tb.errors {
+		for _, line := range strings.Split(msg, "\n") {
+			// testify indents the lines of the message with a tab, and the failures are listed indented further.
+			if failure, ok := strings.CutPrefix(line[strings.LastIndex(line, "\t")+1:], "    "); ok {
+				got = append(got, failure)
+			}
+		}
+	}
+	require.Equal(t, failures, got, "errors: %v", tb.errors)
+}
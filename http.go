@@ -0,0 +1,254 @@
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// HTTPResponse is an HTTP response that can be compared with a golden file, either recorded by a handler test or
+// returned by an HTTP client.
+type HTTPResponse interface {
+	*httptest.ResponseRecorder | *http.Response
+}
+
+// AssertHTTPResponse compares a snapshot of the HTTP response (got) with the JSON golden file at want. If they are not
+// equal, the test is marked as failed, but execution continues.
+//
+// The snapshot is a JSON document with the status code, the headers and the body of the response:
+//
+//	{
+//	    "status": 200,
+//	    "headers": {
+//	        "Content-Type": "application/json",
+//	        "Date": "--* SKIPPED *--"
+//	    },
+//	    "body": {
+//	        "name": "John"
+//	    }
+//	}
+//
+// Headers with a single value are strings, and headers with several values are arrays. The Date header is skipped by
+// default, since it changes on every request. Use WithHeaders to only include some headers, and WithSkippedHeaders to
+// skip others, e.g. request IDs. The body is included as JSON when the content type is JSON, e.g. "application/json"
+// or "application/problem+json", and as a string otherwise. It is omitted when empty.
+//
+// All options work on the snapshot, so the GJSON paths of the body's fields start with "body.", e.g.
+// WithSkippedFields("body.id").
+//
+// The body of an *http.Response is read to the end and closed, and then replaced, so it can still be read after the
+// assertion.
+//
+// Example: AssertHTTPResponse(t, "testdata/get_person.json", rec, WithSkippedHeaders("X-Request-Id"))
+func AssertHTTPResponse[T HTTPResponse](t testing.TB, want string, got T, opts ...Option) {
+	t.Helper()
+	compareHTTPResponse(t, false, want, got, opts...)
+}
+
+// RequireHTTPResponse is like AssertHTTPResponse, but if the golden file and the snapshot of the HTTP response are not
+// equal, the test is marked as failed and execution stops.
+func RequireHTTPResponse[T HTTPResponse](t testing.TB, want string, got T, opts ...Option) {
+	t.Helper()
+	compareHTTPResponse(t, true, want, got, opts...)
+}
+
+func compareHTTPResponse[T HTTPResponse](t testing.TB, failNow bool, want string, got T, opts ...Option) {
+	t.Helper()
+
+	var resp *http.Response
+	switch v := any(got).(type) {
+	case *httptest.ResponseRecorder:
+		if v != nil {
+			resp = v.Result()
+		}
+	case *http.Response:
+		resp = v
+	}
+	if resp == nil {
+		Fail(t, failNow, "HTTP response is nil", "golden file = %s", want)
+		return
+	}
+
+	snapshot, err := newHTTPSnapshot(resp, opts)
+	if !NoError(t, failNow, err, "reading HTTP response") {
+		return
+	}
+
+	data, err := marshalSnapshot(snapshot)
+	if !NoError(t, failNow, err, "marshalling HTTP response") {
+		return
+	}
+
+	compareJSON(t, failNow, want, data, append(opts, envOptions(t, want)...)...)
+}
+
+// WithHeaders only includes the headers with the names in the snapshot of an HTTP response or request. The names are
+// matched case-insensitively. Without this option, all headers are included.
+//
+// NOTE! This option only affects the snapshots of HTTP responses and requests, e.g. AssertHTTPResponse and
+// AssertRequests.
+func WithHeaders(names ...string) Option {
+	return httpOption{configure: func(c *httpSnapshotConfig) {
+		if c.included == nil {
+			c.included = make(map[string]bool)
+		}
+		for _, name := range names {
+			c.included[textproto.CanonicalMIMEHeaderKey(name)] = true
+		}
+	}}
+}
+
+// WithSkippedHeaders replaces the values of the headers with the names with "--* SKIPPED *--" in the snapshot of an
+// HTTP response or request. This is useful for headers that change on every request, e.g. request IDs, and for
+// secrets, e.g. API keys. The names are matched case-insensitively, and headers that are missing are ignored.
+//
+// NOTE! This option only affects the snapshots of HTTP responses and requests, e.g. AssertHTTPResponse and
+// AssertRequests.
+func WithSkippedHeaders(names ...string) Option {
+	return httpOption{configure: func(c *httpSnapshotConfig) {
+		for _, name := range names {
+			c.skipped[textproto.CanonicalMIMEHeaderKey(name)] = true
+		}
+	}}
+}
+
+// httpOption implements Option for configuring the snapshot of an HTTP response or request. It is read before the
+// snapshot is taken, so applying it to the document does nothing.
+type httpOption struct {
+	configure func(c *httpSnapshotConfig)
+}
+
+func (h httpOption) Apply(*Document, string) error {
+	return nil
+}
+
+func (h httpOption) IsType() OptionType {
+	return OptionTypeConfig
+}
+
+// httpSnapshotConfig configures which headers are included in the snapshot of an HTTP response or request.
+type httpSnapshotConfig struct {
+	// included are the canonical names of the headers to include. If nil, all headers are included.
+	included map[string]bool
+	// skipped are the canonical names of the headers whose values are skipped.
+	skipped map[string]bool
+	// skippedQuery are the names of the query parameters whose values are skipped.
+	skippedQuery map[string]bool
+}
+
+// httpSnapshot is the JSON document an HTTP response is compared as.
+type httpSnapshot struct {
+	Status  int             `json:"status"`
+	Headers map[string]any  `json:"headers,omitempty"`
+	Body    json.RawMessage `json:"body,omitempty"`
+}
+
+// newHTTPSnapshot returns the snapshot of the HTTP response, configured by the options.
+func newHTTPSnapshot(resp *http.Response, opts []Option) (httpSnapshot, error) {
+	config := newHTTPSnapshotConfig(opts, "Date")
+	body, err := readHTTPBody(resp)
+	if err != nil {
+		return httpSnapshot{}, err
+	}
+	snapshotBody, err := newHTTPBodySnapshot(resp.Header, body)
+	if err != nil {
+		return httpSnapshot{}, err
+	}
+	return httpSnapshot{
+		Status:  resp.StatusCode,
+		Headers: newHTTPHeadersSnapshot(resp.Header, config),
+		Body:    snapshotBody,
+	}, nil
+}
+
+// newHTTPSnapshotConfig returns the configuration of the options, with the headers skipped by default.
+func newHTTPSnapshotConfig(opts []Option, skippedByDefault ...string) httpSnapshotConfig {
+	config := httpSnapshotConfig{skipped: make(map[string]bool), skippedQuery: make(map[string]bool)}
+	for _, name := range skippedByDefault {
+		config.skipped[name] = true
+	}
+	for _, opt := range opts {
+		if o, ok := opt.(httpOption); ok {
+			o.configure(&config)
+		}
+	}
+	return config
+}
+
+// newHTTPHeadersSnapshot returns the headers included by the configuration, with the values of skipped headers
+// replaced. Headers with a single value are strings, and headers with several values are arrays.
+func newHTTPHeadersSnapshot(header http.Header, config httpSnapshotConfig) map[string]any {
+	headers := make(map[string]any)
+	for name, values := range header {
+		name = textproto.CanonicalMIMEHeaderKey(name)
+		switch {
+		case config.included != nil && !config.included[name]:
+			continue
+		case config.skipped[name]:
+			headers[name] = "--* SKIPPED *--"
+		case len(values) == 1:
+			headers[name] = values[0]
+		default:
+			headers[name] = values
+		}
+	}
+	return headers
+}
+
+// newHTTPBodySnapshot returns the body as JSON when the content type is JSON, and as a JSON string otherwise. It
+// returns nil for an empty body.
+func newHTTPBodySnapshot(header http.Header, body []byte) (json.RawMessage, error) {
+	if len(body) == 0 {
+		return nil, nil
+	}
+	if isJSONContentType(header.Get("Content-Type")) && json.Valid(body) {
+		return body, nil
+	}
+	return json.Marshal(string(body))
+}
+
+// marshalSnapshot marshals the snapshot of an HTTP response or request without escaping HTML characters, which are
+// common in URLs and bodies, e.g. "&" in query strings.
+func marshalSnapshot(snapshot any) (RawJSON, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(snapshot); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// readHTTPBody reads the body of the HTTP response to the end, closes it, and replaces it with a reader of the same
+// content.
+func readHTTPBody(resp *http.Response) ([]byte, error) {
+	if resp.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading body: %w", err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		return nil, fmt.Errorf("closing body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// isJSONContentType reports whether the content type is JSON, i.e. "application/json" or a type with the "+json"
+// suffix.
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
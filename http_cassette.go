@@ -0,0 +1,297 @@
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Cassette is an http.RoundTripper that records the HTTP interactions of a client, i.e. its requests and the responses
+// to them, to a JSON golden file, and replays them in later test runs. This makes it possible to run integration
+// tests against a recording of a partner API, or a local stand-in of it, instead of the API itself.
+//
+// The cassette records when the golden file is updated, i.e. when the environment variable "UPDATE_GOLDENS" is set to
+// "1" or UpdateGoldenFiles is passed, and when a missing golden file is created, see CreateMissingGoldenFiles. It then
+// sends the requests with its transport, and writes the golden file when the test finishes. Otherwise, it replays the
+// responses from the golden file without sending any requests, and a request that matches none of the recorded ones
+// fails the test.
+//
+// The golden file is an array of interactions:
+//
+//	[
+//	    {
+//	        "request": {
+//	            "method": "POST",
+//	            "url": "/v1/tokens?nonce=--* SKIPPED *--",
+//	            "headers": {
+//	                "Authorization": "--* SKIPPED *--"
+//	            }
+//	        },
+//	        "response": {
+//	            "status": 201,
+//	            "headers": {
+//	                "Content-Type": "application/json"
+//	            },
+//	            "body": {
+//	                "token": "--* SKIPPED *--"
+//	            }
+//	        }
+//	    }
+//	]
+//
+// The requests and responses are the snapshots of AssertRequests and AssertHTTPResponse, and the options are the
+// same: WithHeaders, WithSkippedHeaders, WithSkippedQueryParams, and WithSkippedFields and other options on the whole
+// array, e.g. WithSkippedFields("0.response.body.token"). So secrets and timestamps never land in the golden file.
+// The Authorization header of requests, and the Date header of responses, are skipped by default.
+//
+// The URLs of the requests have no scheme or host, so a cassette recorded against a local stand-in on a random port
+// can be replayed with any base URL. A request matches a recorded one if their snapshots are equal, where a skipped
+// value in the recorded request matches any value. Each recorded interaction is replayed once, in the order the
+// requests are sent. Skipped values in recorded responses are replayed as "--* SKIPPED *--".
+type Cassette struct {
+	t         testing.TB
+	path      string
+	transport http.RoundTripper
+	opts      []Option
+	recording bool
+
+	mu           sync.Mutex
+	interactions []cassetteInteraction
+	// replayed are the indexes of the recorded interactions that have been replayed.
+	replayed map[int]bool
+}
+
+// cassetteInteraction is a request and the response to it, as stored in the golden file.
+type cassetteInteraction struct {
+	Request  requestSnapshot `json:"request"`
+	Response httpSnapshot    `json:"response"`
+}
+
+// NewCassette returns a Cassette that records the HTTP interactions to the golden file at path, or replays them from
+// it. If the transport is nil, http.DefaultTransport is used for recording.
+//
+// Example:
+//
+//	cassette := golden.NewCassette(t, "testdata/partner/get_token.json", nil,
+//	    golden.WithSkippedFields("0.response.body.token"))
+//	client := partner.NewClient("http://localhost:8080", &http.Client{Transport: cassette})
+func NewCassette(t testing.TB, path string, transport http.RoundTripper, opts ...Option) *Cassette {
+	t.Helper()
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	c := &Cassette{t: t, path: path, transport: transport, opts: opts, replayed: make(map[int]bool)}
+
+	var update, createMissing bool
+	for _, opt := range append(opts, envOptions(t, path)...) {
+		switch opt.(type) {
+		case updateGoldenFilesOption:
+			update = true
+		case createMissingGoldenFilesOption:
+			createMissing = true
+		}
+	}
+	_, err := os.Stat(path)
+	c.recording = update || (createMissing && errors.Is(err, os.ErrNotExist))
+	if c.recording {
+		t.Cleanup(c.write)
+		return c
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err, "reading cassette, record it by running the test with UPDATE_GOLDENS=1")
+	require.NoError(t, json.Unmarshal(stripJSONComments(data), &c.interactions), "parsing cassette = %s", path)
+	return c
+}
+
+// RoundTrip records the request and the response to it when recording, and otherwise replays the response to the
+// matching recorded request. Requests are recorded with their path and query only, so that recordings made against a
+// stand-in on a random port, e.g. an httptest.Server, can be replayed against any host.
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := &RequestRecorder{transport: c.transport}
+	u := &url.URL{Path: req.URL.Path, RawPath: req.URL.RawPath, RawQuery: req.URL.RawQuery}
+	if !c.recording {
+		if _, err := rec.record(req, u); err != nil {
+			return nil, err
+		}
+		return c.replay(req, rec.recorded()[0])
+	}
+
+	resp, err := rec.roundTrip(req, u)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.record(rec.recorded()[0], resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// record adds the interaction to the cassette. The body of the response is replaced, so that it can still be read.
+func (c *Cassette) record(req recordedRequest, resp *http.Response) error {
+	request, err := newRequestSnapshot(req, newHTTPSnapshotConfig(c.opts, "Authorization"))
+	if err != nil {
+		return err
+	}
+	response, err := newHTTPSnapshot(resp, c.opts)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, cassetteInteraction{Request: request, Response: response})
+	return nil
+}
+
+// write writes the recorded interactions to the golden file, applying the options.
+func (c *Cassette) write() {
+	c.t.Helper()
+	c.mu.Lock()
+	interactions := append([]cassetteInteraction{}, c.interactions...)
+	c.mu.Unlock()
+
+	data, err := marshalSnapshot(interactions)
+	if !NoError(c.t, false, err, "marshalling cassette") {
+		return
+	}
+	compareJSON(c.t, false, c.path, data, append(c.opts, envOptions(c.t, c.path)...)...)
+}
+
+// replay returns the response of the first recorded interaction, which has not been replayed yet, whose request
+// matches the request. If there is none, the test is marked as failed, and an error is returned.
+func (c *Cassette) replay(req *http.Request, recorded recordedRequest) (*http.Response, error) {
+	request, err := newRequestSnapshot(recorded, newHTTPSnapshotConfig(c.opts, "Authorization"))
+	if err != nil {
+		return nil, err
+	}
+	data, err := marshalSnapshot(request)
+	if err != nil {
+		return nil, err
+	}
+	var got any
+	if err := json.Unmarshal(data, &got); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, interaction := range c.interactions {
+		if c.replayed[i] {
+			continue
+		}
+		want, err := marshalSnapshot(interaction.Request)
+		if err != nil {
+			return nil, err
+		}
+		var wantValue any
+		if err := json.Unmarshal(want, &wantValue); err != nil {
+			return nil, err
+		}
+		if matchSnapshot(wantValue, got) {
+			c.replayed[i] = true
+			return newCassetteResponse(req, interaction.Response)
+		}
+	}
+
+	Fail(c.t, false, "unmatched request", "cassette = %s\nno recorded interaction matches the request:\n%s", c.path,
+		strings.TrimSpace(string(data)))
+	return nil, fmt.Errorf("golden: no recorded interaction in %s matches %s %s", c.path, req.Method, req.URL)
+}
+
+// matchSnapshot reports whether the value of a snapshot matches the recorded one. A recorded "--* SKIPPED *--" matches
+// any value, and within a string, e.g. a URL, it matches any text.
+func matchSnapshot(recorded, got any) bool {
+	switch want := recorded.(type) {
+	case map[string]any:
+		gotMap, ok := got.(map[string]any)
+		if !ok || len(gotMap) != len(want) {
+			return false
+		}
+		for k, v := range want {
+			gotValue, ok := gotMap[k]
+			if !ok || !matchSnapshot(v, gotValue) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		gotSlice, ok := got.([]any)
+		if !ok || len(gotSlice) != len(want) {
+			return false
+		}
+		for i := range want {
+			if !matchSnapshot(want[i], gotSlice[i]) {
+				return false
+			}
+		}
+		return true
+	case string:
+		if !strings.Contains(want, "--* SKIPPED *--") {
+			return want == got
+		}
+		if want == "--* SKIPPED *--" {
+			return true
+		}
+		gotString, ok := got.(string)
+		if !ok {
+			return false
+		}
+		parts := strings.Split(want, "--* SKIPPED *--")
+		for i := range parts {
+			parts[i] = regexp.QuoteMeta(parts[i])
+		}
+		return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$").MatchString(gotString)
+	default:
+		return recorded == got
+	}
+}
+
+// newCassetteResponse returns the recorded response to the request. The body is replayed as JSON when the content type
+// is JSON, and as text otherwise.
+func newCassetteResponse(req *http.Request, recorded httpSnapshot) (*http.Response, error) {
+	header := make(http.Header)
+	for name, value := range recorded.Headers {
+		switch v := value.(type) {
+		case string:
+			header.Add(name, v)
+		case []any:
+			for _, s := range v {
+				header.Add(name, fmt.Sprint(s))
+			}
+		}
+	}
+
+	var body []byte
+	if len(recorded.Body) > 0 {
+		var text string
+		if isJSONContentType(header.Get("Content-Type")) || json.Unmarshal(recorded.Body, &text) != nil {
+			body = recorded.Body
+		} else {
+			body = []byte(text)
+		}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
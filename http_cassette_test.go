@@ -0,0 +1,124 @@
+package golden
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// tokenStandIn is a local stand-in of a partner API, which issues a new token on every request.
+func tokenStandIn(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/v1/tokens" {
+		http.NotFound(w, r)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write([]byte(`{"token":"tok-` + r.URL.Query().Get("nonce") + `","scope":` + string(body) + `}`))
+}
+
+// requestToken requests a token with the nonce from the partner API at baseURL.
+func requestToken(t *testing.T, client *http.Client, baseURL, nonce string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, baseURL+"/v1/tokens?nonce="+nonce, strings.NewReader(`"read"`))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Basic c2VjcmV0")
+	req.Header.Set("Content-Type", "application/json")
+	return client.Do(req)
+}
+
+// cassetteOptions are the options the cassettes in the tests are recorded and replayed with.
+func cassetteOptions() []Option {
+	return []Option{
+		WithHeaders("Authorization", "Content-Type"),
+		WithSkippedQueryParams("nonce"),
+		WithSkippedFields("0.response.body.token"),
+	}
+}
+
+func TestCassette_Record(t *testing.T) {
+	/* ---------------------------------- Given --------------------------------- */
+	standIn := httptest.NewServer(http.HandlerFunc(tokenStandIn))
+	defer standIn.Close()
+	path := filepath.Join(t.TempDir(), "get_token.json")
+
+	/* ---------------------------------- When ---------------------------------- */
+	t.Run("record", func(t *testing.T) {
+		cassette := NewCassette(t, path, nil, append(cassetteOptions(), UpdateGoldenFiles())...)
+		resp, err := requestToken(t, &http.Client{Transport: cassette}, standIn.URL, "n-1")
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"token":"tok-n-1","scope":"read"}`, string(body), "response should be passed through")
+	})
+
+	/* ---------------------------------- Then ---------------------------------- */
+	AssertJSON(t, "testdata/cassette/get_token.json", RawJSON(readFile(t, path)))
+}
+
+func TestCassette_Replay(t *testing.T) {
+	/* ---------------------------------- Given --------------------------------- */
+	cassette := NewCassette(t, "testdata/cassette/get_token.json", nil, cassetteOptions()...)
+
+	/* ---------------------------------- When ---------------------------------- */
+	resp, err := requestToken(t, &http.Client{Transport: cassette}, "http://localhost:8080", "n-2")
+
+	/* ---------------------------------- Then ---------------------------------- */
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"token":"--* SKIPPED *--","scope":"read"}`, string(body))
+}
+
+func TestCassette_UnmatchedRequest(t *testing.T) {
+	type given struct {
+		baseURL string
+		// replays is the number of requests sent
+		replays int
+	}
+	type test struct {
+		name  string
+		given given
+	}
+	tests := []test{
+		{
+			name:  "fails when the request is different",
+			given: given{baseURL: "http://localhost:8080/v2", replays: 1},
+		},
+		{
+			name:  "fails when the recorded interaction has already been replayed",
+			given: given{baseURL: "http://localhost:8080", replays: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- Given --------------------------------- */
+			// Replay the cassette even when the golden files are being updated, since only replaying fails on
+			// unmatched requests.
+			t.Setenv("UPDATE_GOLDENS", "")
+			t.Setenv("CREATE_GOLDENS", "")
+			tb := newFakeTB(t.Name()) // test result recorder
+			cassette := NewCassette(tb, "testdata/cassette/get_token.json", nil, cassetteOptions()...)
+			client := &http.Client{Transport: cassette}
+
+			/* ---------------------------------- When ---------------------------------- */
+			var err error
+			for i := 0; i < tt.given.replays; i++ {
+				_, err = requestToken(t, client, tt.given.baseURL, "n-2")
+			}
+
+			/* ---------------------------------- Then ---------------------------------- */
+			require.ErrorContains(t, err, "no recorded interaction in testdata/cassette/get_token.json matches POST")
+			require.True(t, tb.Failed())
+			require.Len(t, tb.errors, 1)
+			require.Contains(t, tb.errors[0], "unmatched request")
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// RequestRecorder records the outbound requests of an HTTP client, so that they can be compared with golden files
+// with AssertRequests or AssertRequest. This locks down exactly what a client sends to a third-party API.
+//
+// It is either used as the client's http.RoundTripper, see NewRequestRecorder, or records the requests received by a
+// test server, see NewRecordingServer. It is safe for concurrent use.
+type RequestRecorder struct {
+	transport http.RoundTripper
+
+	mu       sync.Mutex
+	requests []recordedRequest
+}
+
+// recordedRequest is a request as it was sent, recorded before the body was consumed.
+type recordedRequest struct {
+	method string
+	url    *url.URL
+	header http.Header
+	body   []byte
+}
+
+// NewRequestRecorder returns a RequestRecorder that records the requests and sends them with the transport. If the
+// transport is nil, http.DefaultTransport is used.
+//
+// Example:
+//
+//	rec := golden.NewRequestRecorder(nil)
+//	client := &http.Client{Transport: rec}
+func NewRequestRecorder(transport http.RoundTripper) *RequestRecorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &RequestRecorder{transport: transport}
+}
+
+// NewRecordingServer starts a test server that records the requests it receives and responds with the handler. The
+// server is closed when the test finishes. Point the client under test at the server's URL.
+//
+// The requests recorded by the server have the headers added by the client's transport, e.g. "User-Agent" and
+// "Accept-Encoding", and their URLs have no scheme or host, since the port of the server changes between runs.
+//
+// Example:
+//
+//	server, rec := golden.NewRecordingServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//	    w.WriteHeader(http.StatusNoContent)
+//	}))
+//	client := payments.NewClient(server.URL)
+func NewRecordingServer(t testing.TB, handler http.Handler) (*httptest.Server, *RequestRecorder) {
+	t.Helper()
+	rec := &RequestRecorder{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := rec.record(r, &url.URL{Path: r.URL.Path, RawQuery: r.URL.RawQuery})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, rec
+}
+
+// RoundTrip records the request and sends it with the recorder's transport.
+func (r *RequestRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r.roundTrip(req, req.URL)
+}
+
+// roundTrip records the request with the URL, and sends it with the recorder's transport.
+func (r *RequestRecorder) roundTrip(req *http.Request, u *url.URL) (*http.Response, error) {
+	body, err := r.record(req, u)
+	if err != nil {
+		return nil, err
+	}
+	// RoundTrip must not modify the request, so a clone with a new body is sent instead.
+	clone := req.Clone(req.Context())
+	if req.Body != nil {
+		clone.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return r.transport.RoundTrip(clone)
+}
+
+// record reads and closes the body of the request, and records the request with the URL. It returns the body.
+func (r *RequestRecorder) record(req *http.Request, u *url.URL) ([]byte, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, fmt.Errorf("recording request: reading body: %w", err)
+		}
+		if err := req.Body.Close(); err != nil {
+			return nil, fmt.Errorf("recording request: closing body: %w", err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, recordedRequest{
+		method: req.Method,
+		url:    u,
+		header: req.Header.Clone(),
+		body:   body,
+	})
+	return body, nil
+}
+
+// recorded returns a copy of the recorded requests.
+func (r *RequestRecorder) recorded() []recordedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]recordedRequest(nil), r.requests...)
+}
+
+// AssertRequests compares snapshots of all requests recorded by rec, in the order they were sent, with the JSON golden
+// file at want. If they are not equal, e.g. because a request was added, removed or reordered, the test is marked as
+// failed, but execution continues.
+//
+// The golden file is an array with a snapshot per request:
+//
+//	[
+//	    {
+//	        "method": "POST",
+//	        "url": "https://api.example.com/v1/charges?amount=100&currency=EUR",
+//	        "headers": {
+//	            "Authorization": "--* SKIPPED *--",
+//	            "Content-Type": "application/json"
+//	        },
+//	        "body": {
+//	            "nonce": "--* SKIPPED *--"
+//	        }
+//	    }
+//	]
+//
+// The query parameters of the URL are sorted by name. The Authorization header is skipped by default, so that secrets
+// don't end up in golden files. Use WithHeaders, WithSkippedHeaders and WithSkippedQueryParams to select what is
+// included, and WithSkippedFields for the body, e.g. WithSkippedFields("0.body.nonce"). The body is included as JSON
+// when the content type is JSON, and as a string otherwise.
+func AssertRequests(t testing.TB, want string, rec *RequestRecorder, opts ...Option) {
+	t.Helper()
+	compareRequests(t, false, want, rec, opts...)
+}
+
+// RequireRequests is like AssertRequests, but if the golden file and the snapshots of the requests are not equal, the
+// test is marked as failed and execution stops.
+func RequireRequests(t testing.TB, want string, rec *RequestRecorder, opts ...Option) {
+	t.Helper()
+	compareRequests(t, true, want, rec, opts...)
+}
+
+// AssertRequest compares a snapshot of the request at index, counting from 0, of the requests recorded by rec with
+// the JSON golden file at want. This gives each request a golden file of its own. If they are not equal, or the
+// request was not sent, the test is marked as failed, but execution continues.
+//
+// The snapshot is like those of AssertRequests, so the GJSON paths of the body's fields start with "body.", e.g.
+// WithSkippedFields("body.nonce").
+func AssertRequest(t testing.TB, want string, rec *RequestRecorder, index int, opts ...Option) {
+	t.Helper()
+	compareRequest(t, false, want, rec, index, opts...)
+}
+
+// RequireRequest is like AssertRequest, but if the golden file and the snapshot of the request are not equal, the
+// test is marked as failed and execution stops.
+func RequireRequest(t testing.TB, want string, rec *RequestRecorder, index int, opts ...Option) {
+	t.Helper()
+	compareRequest(t, true, want, rec, index, opts...)
+}
+
+func compareRequests(t testing.TB, failNow bool, want string, rec *RequestRecorder, opts ...Option) {
+	t.Helper()
+
+	config := newHTTPSnapshotConfig(opts, "Authorization")
+	snapshots := []requestSnapshot{}
+	for _, req := range rec.recorded() {
+		snapshot, err := newRequestSnapshot(req, config)
+		if !NoError(t, failNow, err, "taking snapshot of request") {
+			return
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	data, err := marshalSnapshot(snapshots)
+	if !NoError(t, failNow, err, "marshalling requests") {
+		return
+	}
+
+	compareJSON(t, failNow, want, data, append(opts, envOptions(t, want)...)...)
+}
+
+func compareRequest(t testing.TB, failNow bool, want string, rec *RequestRecorder, index int, opts ...Option) {
+	t.Helper()
+
+	requests := rec.recorded()
+	if index < 0 || index >= len(requests) {
+		Fail(t, failNow, fmt.Sprintf("request %d was not sent, %d requests were recorded", index, len(requests)),
+			"golden file = %s", want)
+		return
+	}
+	snapshot, err := newRequestSnapshot(requests[index], newHTTPSnapshotConfig(opts, "Authorization"))
+	if !NoError(t, failNow, err, "taking snapshot of request") {
+		return
+	}
+	data, err := marshalSnapshot(snapshot)
+	if !NoError(t, failNow, err, "marshalling request") {
+		return
+	}
+
+	compareJSON(t, failNow, want, data, append(opts, envOptions(t, want)...)...)
+}
+
+// WithSkippedQueryParams replaces the values of the query parameters with the names with "--* SKIPPED *--" in the
+// URLs of the snapshots of HTTP requests. This is useful for parameters that change on every request, e.g. nonces and
+// timestamps. The names are case-sensitive, and parameters that are missing are ignored.
+//
+// NOTE! This option only affects AssertRequests, AssertRequest and their Require variants.
+func WithSkippedQueryParams(names ...string) Option {
+	return httpOption{configure: func(c *httpSnapshotConfig) {
+		for _, name := range names {
+			c.skippedQuery[name] = true
+		}
+	}}
+}
+
+// requestSnapshot is the JSON document an HTTP request is compared as.
+type requestSnapshot struct {
+	Method  string          `json:"method"`
+	URL     string          `json:"url"`
+	Headers map[string]any  `json:"headers,omitempty"`
+	Body    json.RawMessage `json:"body,omitempty"`
+}
+
+// newRequestSnapshot returns the snapshot of the recorded request, configured by config.
+func newRequestSnapshot(req recordedRequest, config httpSnapshotConfig) (requestSnapshot, error) {
+	body, err := newHTTPBodySnapshot(req.header, req.body)
+	if err != nil {
+		return requestSnapshot{}, err
+	}
+	return requestSnapshot{
+		Method:  req.method,
+		URL:     snapshotURL(req.url, config),
+		Headers: newHTTPHeadersSnapshot(req.header, config),
+		Body:    body,
+	}, nil
+}
+
+// snapshotURL returns the URL with its query parameters sorted by name, and the values of skipped parameters
+// replaced. Parameters with the same name keep their order. The fragment is dropped, since it is never sent.
+func snapshotURL(u *url.URL, config httpSnapshotConfig) string {
+	withoutQuery := *u
+	withoutQuery.RawQuery, withoutQuery.Fragment, withoutQuery.RawFragment = "", "", ""
+	query := u.Query()
+	if len(query) == 0 {
+		return withoutQuery.String()
+	}
+
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var params []string
+	for _, name := range names {
+		for _, value := range query[name] {
+			if config.skippedQuery[name] {
+				value = "--* SKIPPED *--"
+			} else {
+				value = url.QueryEscape(value)
+			}
+			params = append(params, url.QueryEscape(name)+"="+value)
+		}
+	}
+	return withoutQuery.String() + "?" + strings.Join(params, "&")
+}
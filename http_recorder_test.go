@@ -0,0 +1,113 @@
+package golden
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// roundTripperFunc is an http.RoundTripper that responds with the function, without sending the request.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// noContentTransport responds to every request with 204 No Content.
+var noContentTransport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusNoContent, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+})
+
+// chargeClient is a client of a third-party payments API.
+type chargeClient struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// charge creates a charge with a new nonce, and then fetches it.
+func (c chargeClient) charge(nonce string) error {
+	body := `{"amount":100,"currency":"EUR","nonce":"` + nonce + `"}`
+	chargesURL := c.baseURL + "/v1/charges?idempotency_key=" + nonce + "&expand=customer"
+	req, err := http.NewRequest(http.MethodPost, chargesURL, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer sk_test_secret")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	resp, err = c.httpClient.Get(c.baseURL + "/v1/charges/ch_1?" + url.Values{"nonce": {nonce}}.Encode())
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func TestAssertRequests(t *testing.T) {
+	/* ---------------------------------- Given --------------------------------- */
+	rec := NewRequestRecorder(noContentTransport)
+	client := chargeClient{httpClient: &http.Client{Transport: rec}, baseURL: "https://api.example.com"}
+	tb := newFakeTB(t.Name()) // test result recorder
+
+	/* ---------------------------------- When ---------------------------------- */
+	err := client.charge("n-1")
+	AssertRequests(tb, "testdata/assert_requests/charge.json", rec,
+		WithSkippedQueryParams("idempotency_key", "nonce"),
+		WithSkippedFields("0.body.nonce"),
+	)
+
+	/* ---------------------------------- Then ---------------------------------- */
+	require.NoError(t, err)
+	require.False(t, tb.Failed(), "errors: %v", tb.errors)
+}
+
+func TestAssertRequests_Failure(t *testing.T) {
+	/* ---------------------------------- Given --------------------------------- */
+	rec := NewRequestRecorder(noContentTransport)
+	client := &http.Client{Transport: rec}
+	tb := newFakeTB(t.Name()) // test result recorder
+
+	/* ---------------------------------- When ---------------------------------- */
+	resp, err := client.Get("https://api.example.com/v1/charges/ch_1?nonce=n-1")
+	require.NoError(t, err)
+	resp.Body.Close()
+	AssertRequests(tb, "testdata/assert_requests/charge.json", rec,
+		WithSkippedQueryParams("idempotency_key", "nonce"),
+	)
+
+	/* ---------------------------------- Then ---------------------------------- */
+	require.True(t, tb.Failed())
+	require.Contains(t, tb.errors[0], `changed      0.method: "POST" => "GET"`)
+	require.Contains(t, tb.errors[0], `removed      1: {"method":"GET"`)
+}
+
+func TestAssertRequest(t *testing.T) {
+	/* ---------------------------------- Given --------------------------------- */
+	server, rec := NewRecordingServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	client := chargeClient{httpClient: server.Client(), baseURL: server.URL}
+	tb := newFakeTB(t.Name()) // test result recorder
+
+	/* ---------------------------------- When ---------------------------------- */
+	err := client.charge("n-1")
+	AssertRequest(tb, "testdata/assert_requests/create_charge.json", rec, 0,
+		WithHeaders("Authorization", "Content-Type"),
+		WithSkippedQueryParams("idempotency_key"),
+		WithSkippedFields("body.nonce"),
+	)
+	AssertRequest(tb, "testdata/assert_requests/create_charge.json", rec, 2)
+
+	/* ---------------------------------- Then ---------------------------------- */
+	require.NoError(t, err)
+	require.True(t, tb.Failed())
+	require.Len(t, tb.errors, 1, "only the request that was not sent should fail")
+	require.Contains(t, tb.errors[0], "request 2 was not sent, 2 requests were recorded")
+}
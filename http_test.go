@@ -0,0 +1,141 @@
+package golden
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// personHandler responds with a person as JSON, with a new request ID on every request.
+func personHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-Request-Id", r.Header.Get("X-Request-Id"))
+	w.Header().Add("Vary", "Accept")
+	w.Header().Add("Vary", "Accept-Encoding")
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write([]byte(`{"id":"3f2b8c1e","name":"John","age":30}`))
+}
+
+// newPersonRequest returns a request for personHandler with the request ID.
+func newPersonRequest(requestID string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/people", nil)
+	req.Header.Set("X-Request-Id", requestID)
+	return req
+}
+
+func TestAssertHTTPResponse(t *testing.T) {
+	type args struct {
+		want    string
+		handler http.HandlerFunc
+		options []Option
+	}
+	type given struct {
+		args args
+	}
+	type test struct {
+		name  string
+		given given
+	}
+	tests := []test{
+		{
+			name: "passes when the golden file equals the response",
+			given: given{
+				args: args{
+					want:    "testdata/assert_http_response/json_body.json",
+					handler: personHandler,
+					options: []Option{WithSkippedHeaders("x-request-id"), WithSkippedFields("body.id")},
+				},
+			},
+		},
+		{
+			name: "passes with only the allowed headers",
+			given: given{
+				args: args{
+					want:    "testdata/assert_http_response/allowed_headers.json",
+					handler: personHandler,
+					options: []Option{WithHeaders("Content-Type"), WithSkippedFields("body.id")},
+				},
+			},
+		},
+		{
+			name: "passes when the body is not JSON",
+			given: given{
+				args: args{
+					want: "testdata/assert_http_response/text_body.json",
+					handler: func(w http.ResponseWriter, _ *http.Request) {
+						http.Error(w, "person not found", http.StatusNotFound)
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- Given --------------------------------- */
+			tb := newFakeTB(t.Name()) // test result recorder
+			rec := httptest.NewRecorder()
+			tt.given.args.handler(rec, newPersonRequest("req-1"))
+
+			/* ---------------------------------- When ---------------------------------- */
+			AssertHTTPResponse(tb, tt.given.args.want, rec, tt.given.args.options...)
+
+			/* ---------------------------------- Then ---------------------------------- */
+			require.False(t, tb.Failed(), "errors: %v", tb.errors)
+		})
+	}
+}
+
+func TestAssertHTTPResponse_Response(t *testing.T) {
+	/* ---------------------------------- Given --------------------------------- */
+	server := httptest.NewServer(http.HandlerFunc(personHandler))
+	defer server.Close()
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/people", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Request-Id", "req-2")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	tb := newFakeTB(t.Name()) // test result recorder
+
+	/* ---------------------------------- When ---------------------------------- */
+	AssertHTTPResponse(tb, "testdata/assert_http_response/response.json", resp,
+		WithSkippedHeaders("X-Request-Id"), WithSkippedFields("body.id"))
+
+	/* ---------------------------------- Then ---------------------------------- */
+	require.False(t, tb.Failed(), "errors: %v", tb.errors)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id":"3f2b8c1e","name":"John","age":30}`, string(body), "body should still be readable")
+}
+
+func TestAssertHTTPResponse_Failure(t *testing.T) {
+	/* ---------------------------------- Given --------------------------------- */
+	tb := newFakeTB(t.Name()) // test result recorder
+	rec := httptest.NewRecorder()
+	personHandler(rec, newPersonRequest("req-1"))
+
+	/* ---------------------------------- When ---------------------------------- */
+	AssertHTTPResponse(tb, "testdata/assert_http_response/json_body.json", rec, WithSkippedFields("body.id"))
+
+	/* ---------------------------------- Then ---------------------------------- */
+	require.True(t, tb.Failed())
+	require.Contains(t, tb.errors[0], `changed      headers.X-Request-Id: "--* SKIPPED *--" => "req-1"`)
+}
+
+func TestAssertHTTPResponse_Nil(t *testing.T) {
+	/* ---------------------------------- Given --------------------------------- */
+	tb := newFakeTB(t.Name()) // test result recorder
+
+	/* ---------------------------------- When ---------------------------------- */
+	AssertHTTPResponse(tb, "testdata/assert_http_response/json_body.json", (*http.Response)(nil))
+	AssertHTTPResponse(tb, "testdata/assert_http_response/json_body.json", (*httptest.ResponseRecorder)(nil))
+
+	/* ---------------------------------- Then ---------------------------------- */
+	require.True(t, tb.Failed())
+	require.Len(t, tb.errors, 2)
+	for _, err := range tb.errors {
+		require.Contains(t, err, "HTTP response is nil")
+	}
+}
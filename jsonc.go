@@ -0,0 +1,445 @@
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// commentAnchor describes where a comment is placed relative to the value it is attached to.
+type commentAnchor int
+
+const (
+	// anchorFile is a comment before the top-level value, e.g. one added by WithFileComment.
+	anchorFile commentAnchor = iota
+	// anchorLeading is a comment on its own line before the value.
+	anchorLeading
+	// anchorTrailing is a comment at the end of the line where the value ends, e.g. one added by WithFieldComments.
+	anchorTrailing
+	// anchorOpening is a comment at the end of the line where an object or array value starts.
+	anchorOpening
+	// anchorClosing is a comment on its own line after the last value of an object or array, before it is closed.
+	anchorClosing
+	// anchorFooter is a comment on its own line after the top-level value.
+	anchorFooter
+)
+
+// jsoncValue is the location of a value in a JSONC document.
+type jsoncValue struct {
+	// path is the GJSON path to the value. It is empty for the top-level value.
+	path string
+	// start and end are the byte offsets of the value, end is exclusive.
+	start, end int
+	// startLine and endLine are the zero-based lines of the start and end of the value.
+	startLine, endLine int
+}
+
+// jsoncComment is a comment in a JSONC document, attached to the value it describes.
+type jsoncComment struct {
+	// text is the raw text of the comment, including the comment markers.
+	text string
+	// path is the GJSON path to the value the comment is attached to. It is empty for the top-level value.
+	path   string
+	anchor commentAnchor
+	// offset and line are the byte offset and zero-based line of the start of the comment.
+	offset, line int
+}
+
+// jsoncDocument is the result of scanning a JSONC document.
+type jsoncDocument struct {
+	// values are all values in the document, in the order they start.
+	values []jsoncValue
+	// comments are all comments in the document, in the order they appear.
+	comments []jsoncComment
+}
+
+// value returns the location of the value at path, and whether it exists.
+func (d jsoncDocument) value(path string) (jsoncValue, bool) {
+	for _, v := range d.values {
+		if v.path == path {
+			return v, true
+		}
+	}
+	return jsoncValue{}, false
+}
+
+// hasComment reports whether the document has a comment attached to path with the anchor. If text is non-empty, the
+// comment must also have the same text.
+func (d jsoncDocument) hasComment(path string, anchor commentAnchor, text string) bool {
+	for _, c := range d.comments {
+		if c.path == path && c.anchor == anchor && (text == "" || c.text == text) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsoncFrame is an object or array being scanned.
+type jsoncFrame struct {
+	// value is the index of the object or array in jsoncDocument.values.
+	value int
+	array bool
+	// index is the index of the current element when array is true.
+	index int
+	// key is the current key when array is false.
+	key string
+	// expectKey is true when the next string is a key.
+	expectKey bool
+}
+
+// jsoncScanner scans a JSONC document into its values and comments.
+type jsoncScanner struct {
+	data  []byte
+	lines []int // byte offsets of the start of each line
+	doc   jsoncDocument
+	stack []jsoncFrame
+}
+
+// scanJSONC scans a JSONC document and returns the locations of its values and comments, with every comment attached
+// to a value by its GJSON path.
+func scanJSONC(data []byte) (jsoncDocument, error) {
+	s := &jsoncScanner{data: data, lines: []int{0}}
+	for i, c := range data {
+		if c == '\n' {
+			s.lines = append(s.lines, i+1)
+		}
+	}
+	if err := s.scan(); err != nil {
+		return jsoncDocument{}, err
+	}
+	s.attachComments()
+	return s.doc, nil
+}
+
+func (s *jsoncScanner) scan() error {
+	for i := 0; i < len(s.data); i++ {
+		c := s.data[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+		case c == '/' && i+1 < len(s.data) && s.data[i+1] == '/':
+			end := bytes.IndexByte(s.data[i:], '\n')
+			if end == -1 {
+				end = len(s.data) - i
+			}
+			s.addComment(i, i+end)
+			i += end - 1
+		case c == '/' && i+1 < len(s.data) && s.data[i+1] == '*':
+			end := bytes.Index(s.data[i+2:], []byte("*/"))
+			if end == -1 {
+				return fmt.Errorf("unterminated comment at offset %d", i)
+			}
+			s.addComment(i, i+end+4)
+			i += end + 3
+		case c == '"':
+			end := skipJSONString(s.data, i)
+			if top := s.top(); top != nil && !top.array && top.expectKey {
+				var key string
+				if err := json.Unmarshal(s.data[i:end], &key); err != nil {
+					return fmt.Errorf("invalid key at offset %d: %w", i, err)
+				}
+				top.key = key
+				top.expectKey = false
+			} else {
+				s.addValue(i, end)
+			}
+			i = end - 1
+		case c == '{' || c == '[':
+			idx := s.addValue(i, -1)
+			s.stack = append(s.stack, jsoncFrame{value: idx, array: c == '[', expectKey: c == '{'})
+		case c == '}' || c == ']':
+			top := s.top()
+			if top == nil || top.array != (c == ']') {
+				return fmt.Errorf("unexpected %q at offset %d", c, i)
+			}
+			v := &s.doc.values[top.value]
+			v.end = i + 1
+			v.endLine = s.lineAt(i)
+			s.stack = s.stack[:len(s.stack)-1]
+		case c == ':':
+		case c == ',':
+			if top := s.top(); top != nil {
+				if top.array {
+					top.index++
+				} else {
+					top.expectKey = true
+				}
+			}
+		default:
+			end := i
+			for end < len(s.data) && !bytes.ContainsRune([]byte(" \t\r\n,:]}/"), rune(s.data[end])) {
+				end++
+			}
+			s.addValue(i, end)
+			i = end - 1
+		}
+	}
+	if len(s.stack) > 0 {
+		return errors.New("unexpected end of document")
+	}
+	if len(s.doc.values) == 0 {
+		return errors.New("document has no value")
+	}
+	return nil
+}
+
+// top returns the innermost object or array being scanned, or nil if none.
+func (s *jsoncScanner) top() *jsoncFrame {
+	if len(s.stack) == 0 {
+		return nil
+	}
+	return &s.stack[len(s.stack)-1]
+}
+
+// addValue adds a value starting at start and ending at end, or at its closing bracket if end is -1, and returns its
+// index.
+func (s *jsoncScanner) addValue(start, end int) int {
+	var path string
+	if top := s.top(); top != nil {
+		parent := s.doc.values[top.value].path
+		if top.array {
+			path = joinPath(parent, strconv.Itoa(top.index))
+		} else {
+			path = joinPath(parent, escapePathKey(top.key))
+		}
+	}
+	v := jsoncValue{path: path, start: start, end: end, startLine: s.lineAt(start)}
+	if end != -1 {
+		v.endLine = s.lineAt(end - 1)
+	}
+	s.doc.values = append(s.doc.values, v)
+	return len(s.doc.values) - 1
+}
+
+func (s *jsoncScanner) addComment(start, end int) {
+	text := strings.TrimRight(string(s.data[start:end]), "\r")
+	s.doc.comments = append(s.doc.comments, jsoncComment{text: text, offset: start, line: s.lineAt(start)})
+}
+
+// lineAt returns the zero-based line of the byte offset.
+func (s *jsoncScanner) lineAt(offset int) int {
+	return sort.Search(len(s.lines), func(i int) bool { return s.lines[i] > offset }) - 1
+}
+
+// attachComments attaches every comment to the value it describes.
+func (s *jsoncScanner) attachComments() {
+	root := s.doc.values[0]
+	for i := range s.doc.comments {
+		c := &s.doc.comments[i]
+
+		// A comment on the same line after a value describes that value, e.g. "age": 30, // comment
+		if v, ok := s.lastValueEndingBefore(c.offset, c.line); ok {
+			c.path, c.anchor = v.path, anchorTrailing
+			continue
+		}
+		// A comment on the same line after an opening bracket describes the object or array, e.g. "user": { // comment
+		if v, ok := s.lastValueStartingBefore(c.offset, c.line); ok {
+			c.path, c.anchor = v.path, anchorOpening
+			continue
+		}
+
+		switch {
+		case c.offset < root.start:
+			c.path, c.anchor = "", anchorFile
+		case c.offset > root.end:
+			c.path, c.anchor = "", anchorFooter
+		default:
+			// A comment on its own line describes the next value, unless the enclosing object or array is closed first.
+			container := s.innermostContainer(c.offset)
+			if next, ok := s.nextValue(c.offset); ok && next.start < container.end {
+				c.path, c.anchor = next.path, anchorLeading
+			} else {
+				c.path, c.anchor = container.path, anchorClosing
+			}
+		}
+	}
+}
+
+// lastValueEndingBefore returns the value that ends last on the line, before the offset.
+func (s *jsoncScanner) lastValueEndingBefore(offset, line int) (jsoncValue, bool) {
+	var found jsoncValue
+	var ok bool
+	for _, v := range s.doc.values {
+		if v.endLine == line && v.end <= offset && (!ok || v.end > found.end) {
+			found, ok = v, true
+		}
+	}
+	return found, ok
+}
+
+// lastValueStartingBefore returns the object or array that starts last on the line before the offset, and ends after
+// it.
+func (s *jsoncScanner) lastValueStartingBefore(offset, line int) (jsoncValue, bool) {
+	var found jsoncValue
+	var ok bool
+	for _, v := range s.doc.values {
+		if v.startLine == line && v.start < offset && v.end > offset && (!ok || v.start > found.start) {
+			found, ok = v, true
+		}
+	}
+	return found, ok
+}
+
+// innermostContainer returns the innermost object or array enclosing the offset.
+func (s *jsoncScanner) innermostContainer(offset int) jsoncValue {
+	found := s.doc.values[0]
+	for _, v := range s.doc.values {
+		if v.start < offset && v.end > offset && v.start >= found.start {
+			found = v
+		}
+	}
+	return found
+}
+
+// nextValue returns the first value starting after the offset.
+func (s *jsoncScanner) nextValue(offset int) (jsoncValue, bool) {
+	for _, v := range s.doc.values {
+		if v.start > offset {
+			return v, true
+		}
+	}
+	return jsoncValue{}, false
+}
+
+// mergeComments re-attaches the comments in the old JSONC document to the same GJSON paths in the new one. Comments
+// that already exist in the new document, e.g. because they were added by WithFieldComments or WithFileComment, take
+// precedence over the old ones. It returns the merged document and the comments whose paths no longer exist.
+//
+// Comments that were added by WithFieldComments when the old document was written cannot be told apart from
+// hand-written ones, so they are kept even if the field comment has since been removed.
+func mergeComments(oldData, newData []byte) ([]byte, []jsoncComment, error) {
+	oldDoc, err := scanJSONC(oldData)
+	if err != nil {
+		return nil, nil, fmt.Errorf("scanning old document: %w", err)
+	}
+	if len(oldDoc.comments) == 0 {
+		return newData, nil, nil
+	}
+	newDoc, err := scanJSONC(newData)
+	if err != nil {
+		return nil, nil, fmt.Errorf("scanning new document: %w", err)
+	}
+
+	lines := strings.Split(string(newData), "\n")
+	before := make(map[int][]string) // lines to insert before a line
+	after := make(map[int][]string)  // comments to append to the end of a line
+	var header, footer []string
+	var lost []jsoncComment
+
+	for _, c := range oldDoc.comments {
+		if c.anchor == anchorFile || c.anchor == anchorFooter {
+			if (c.anchor == anchorFile && newDoc.hasComment("", anchorFile, "")) ||
+				(c.anchor == anchorFooter && newDoc.hasComment("", anchorFooter, c.text)) {
+				continue
+			}
+			if c.anchor == anchorFile {
+				header = append(header, c.text)
+			} else {
+				footer = append(footer, c.text)
+			}
+			continue
+		}
+
+		v, ok := newDoc.value(c.path)
+		if !ok {
+			lost = append(lost, c)
+			continue
+		}
+
+		switch c.anchor {
+		case anchorTrailing, anchorOpening:
+			if newDoc.hasComment(c.path, c.anchor, "") {
+				continue
+			}
+			line := v.endLine
+			if c.anchor == anchorOpening {
+				line = v.startLine
+			}
+			after[line] = append(after[line], c.text)
+		case anchorLeading:
+			if newDoc.hasComment(c.path, c.anchor, c.text) {
+				continue
+			}
+			before[v.startLine] = append(before[v.startLine], indentationOf(lines[v.startLine])+c.text)
+		case anchorClosing:
+			if newDoc.hasComment(c.path, c.anchor, c.text) {
+				continue
+			}
+			before[v.endLine] = append(before[v.endLine], indentationOf(lines[v.endLine])+"    "+c.text)
+		}
+	}
+
+	var buf bytes.Buffer
+	if len(header) > 0 {
+		buf.WriteString(strings.Join(header, "\n") + "\n\n")
+	}
+	for i, line := range lines {
+		for _, l := range before[i] {
+			buf.WriteString(l + "\n")
+		}
+		buf.WriteString(line)
+		for _, text := range after[i] {
+			buf.WriteString(" " + text)
+		}
+		if i < len(lines)-1 {
+			buf.WriteString("\n")
+		}
+	}
+	for _, text := range footer {
+		buf.WriteString("\n" + text)
+	}
+	return buf.Bytes(), lost, nil
+}
+
+// indentationOf returns the leading whitespace of the line.
+func indentationOf(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// stripJSONComments removes line (//) and block (/* */) comments from a JSONC document. Comments inside strings are
+// left untouched.
+func stripJSONComments(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '"':
+			end := skipJSONString(data, i)
+			out = append(out, data[i:end]...)
+			i = end - 1
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			if i < len(data) {
+				out = append(out, '\n')
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end == -1 {
+				return out
+			}
+			i += end + 3
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// skipJSONString returns the index just past the end of the JSON string starting at data[start], which must be a
+// double quote.
+func skipJSONString(data []byte, start int) int {
+	for i := start + 1; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(data)
+}
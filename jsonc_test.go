@@ -0,0 +1,234 @@
+package golden
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanJSONC(t *testing.T) {
+	type want struct {
+		// comments are the expected comments, with only their text, path and anchor set
+		comments []jsoncComment
+	}
+	type test struct {
+		name  string
+		given string
+		want  want
+	}
+	tests := []test{
+		{
+			name: "attaches comments to values",
+			given: `/*
+file comment
+*/
+
+{
+    // leading comment
+    "age": 30, // trailing comment
+    "colour": { // opening comment
+        "eyes": "brown"
+        // closing comment
+    },
+    "fav.movies": [
+        "Deer Hunter" /* trailing block comment */
+    ]
+}
+// footer comment`,
+			want: want{
+				comments: []jsoncComment{
+					{text: "/*\nfile comment\n*/", path: "", anchor: anchorFile},
+					{text: "// leading comment", path: "age", anchor: anchorLeading},
+					{text: "// trailing comment", path: "age", anchor: anchorTrailing},
+					{text: "// opening comment", path: "colour", anchor: anchorOpening},
+					{text: "// closing comment", path: "colour", anchor: anchorClosing},
+					{text: "/* trailing block comment */", path: `fav\.movies.0`, anchor: anchorTrailing},
+					{text: "// footer comment", path: "", anchor: anchorFooter},
+				},
+			},
+		},
+		{
+			name: "attaches trailing comment after closing bracket to the object",
+			given: `{
+    "colour": {
+        "eyes": "brown"
+    }, // trailing comment
+    "name": "John"
+}`,
+			want: want{
+				comments: []jsoncComment{
+					{text: "// trailing comment", path: "colour", anchor: anchorTrailing},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- When ---------------------------------- */
+			doc, err := scanJSONC([]byte(tt.given))
+
+			/* ---------------------------------- Then ---------------------------------- */
+			require.NoError(t, err)
+			var got []jsoncComment
+			for _, c := range doc.comments {
+				got = append(got, jsoncComment{text: c.text, path: c.path, anchor: c.anchor})
+			}
+			require.Equal(t, tt.want.comments, got)
+		})
+	}
+}
+
+func TestMergeComments(t *testing.T) {
+	type args struct {
+		old string
+		new string
+	}
+	type given struct {
+		args args
+	}
+	type want struct {
+		merged string
+		// lost are the texts of the comments whose paths no longer exist
+		lost []string
+	}
+	type test struct {
+		name  string
+		given given
+		want  want
+	}
+	tests := []test{
+		{
+			name: "re-attaches hand-written comments to the same paths",
+			given: given{
+				args: args{
+					old: `/*
+file comment
+*/
+
+{
+    // Age in years
+    "age": 30, // Should be 30
+    "name": "John"
+}`,
+					new: `{
+    "age": 31,
+    "name": "Jane"
+}`,
+				},
+			},
+			want: want{
+				merged: `/*
+file comment
+*/
+
+{
+    // Age in years
+    "age": 31, // Should be 30
+    "name": "Jane"
+}`,
+			},
+		},
+		{
+			name: "generated comments take precedence over old ones",
+			given: given{
+				args: args{
+					old: `/*
+old file comment
+*/
+
+{
+    "age": 30, // Old field comment
+    "name": "John" // Hand-written comment
+}`,
+					new: `/*
+new file comment
+*/
+
+{
+    "age": 30, // New field comment
+    "name": "John"
+}`,
+				},
+			},
+			want: want{
+				merged: `/*
+new file comment
+*/
+
+{
+    "age": 30, // New field comment
+    "name": "John" // Hand-written comment
+}`,
+			},
+		},
+		{
+			name: "reports comments whose paths no longer exist",
+			given: given{
+				args: args{
+					old: `{
+    "age": 30, // Should be 30
+    "name": "John"
+}`,
+					new: `{
+    "name": "John"
+}`,
+				},
+			},
+			want: want{
+				merged: `{
+    "name": "John"
+}`,
+				lost: []string{"// Should be 30"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- When ---------------------------------- */
+			merged, lost, err := mergeComments([]byte(tt.given.args.old), []byte(tt.given.args.new))
+
+			/* ---------------------------------- Then ---------------------------------- */
+			require.NoError(t, err)
+			require.Equal(t, tt.want.merged, string(merged))
+			var gotLost []string
+			for _, c := range lost {
+				gotLost = append(gotLost, c.text)
+			}
+			require.Equal(t, tt.want.lost, gotLost)
+		})
+	}
+}
+
+func TestStripJSONComments(t *testing.T) {
+	type test struct {
+		name  string
+		given string
+		want  string
+	}
+	tests := []test{
+		{
+			name:  "removes line comments",
+			given: "{\n    \"age\": 30, // comment\n    \"name\": \"John\" // comment\n}",
+			want:  "{\n    \"age\": 30, \n    \"name\": \"John\" \n}",
+		},
+		{
+			name:  "removes block comments",
+			given: "/*\nfile comment\n*/\n{\"age\": /* inline */ 30}",
+			want:  "\n{\"age\":  30}",
+		},
+		{
+			name:  "keeps comment markers inside strings",
+			given: `{"url": "https://example.com/*path*/"}`,
+			want:  `{"url": "https://example.com/*path*/"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- When ---------------------------------- */
+			got := stripJSONComments([]byte(tt.given))
+
+			/* ---------------------------------- Then ---------------------------------- */
+			require.Equal(t, tt.want, string(got))
+		})
+	}
+}
@@ -70,6 +70,26 @@ func TestOptionInterface(t *testing.T) {
 			option:       UpdateGoldenFiles(),
 			expectedType: OptionTypeModifier,
 		},
+		{
+			name:         "WithSemanticCompare should be config",
+			option:       WithSemanticCompare(),
+			expectedType: OptionTypeConfig,
+		},
+		{
+			name:         "CreateMissingGoldenFiles should be config",
+			option:       CreateMissingGoldenFiles(),
+			expectedType: OptionTypeConfig,
+		},
+		{
+			name:         "WithGoldenDir should be config",
+			option:       WithGoldenDir("testdata"),
+			expectedType: OptionTypeConfig,
+		},
+		{
+			name:         "WriteActualFiles should be config",
+			option:       WriteActualFiles(""),
+			expectedType: OptionTypeConfig,
+		},
 		{
 			name:         "CheckNotZeroTime should be check",
 			option:       CheckNotZeroTime("time", time.RFC3339),
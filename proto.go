@@ -0,0 +1,79 @@
+package golden
+
+import (
+	// The standard error details are registered so that they are resolved in the details of gRPC status errors.
+	_ "google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// WithProtoNames marshals protobuf messages with the field names from the .proto file, e.g. "user_id", instead of
+// their lowerCamelCase JSON names, e.g. "userId".
+//
+// NOTE! This option only affects protobuf messages and gRPC status errors passed as got.
+func WithProtoNames() Option {
+	return protoJSONOption{configure: func(o *protojson.MarshalOptions) { o.UseProtoNames = true }}
+}
+
+// WithEmitUnpopulated marshals protobuf messages with all their fields, including those that are not populated, e.g.
+// zero numbers, empty strings and lists, and unset messages as null. By default, unpopulated fields are omitted.
+//
+// NOTE! This option only affects protobuf messages and gRPC status errors passed as got.
+func WithEmitUnpopulated() Option {
+	return protoJSONOption{configure: func(o *protojson.MarshalOptions) { o.EmitUnpopulated = true }}
+}
+
+// ProtoResolver resolves the message types of google.protobuf.Any values by their type URLs, and extensions by their
+// names. *protoregistry.Types implements it.
+type ProtoResolver interface {
+	protoregistry.ExtensionTypeResolver
+	protoregistry.MessageTypeResolver
+}
+
+// WithProtoResolver resolves the message types of google.protobuf.Any values in protobuf messages with the resolver,
+// instead of the global registry, protoregistry.GlobalTypes.
+//
+// Any values, e.g. the details of gRPC status errors, are rendered as the JSON of the message they hold, with its type
+// URL in the "@type" field. The global registry holds every message type whose generated Go package is linked into
+// the test binary, including the standard error details such as google.rpc.BadRequest and google.rpc.ErrorInfo. An
+// Any value whose type cannot be resolved fails the test with "unable to resolve", in which case either import the Go
+// package of the message type, or use this option.
+//
+// NOTE! This option only affects protobuf messages and gRPC status errors passed as got.
+func WithProtoResolver(resolver ProtoResolver) Option {
+	return protoJSONOption{configure: func(o *protojson.MarshalOptions) { o.Resolver = resolver }}
+}
+
+// protoJSONOption implements Option for configuring how protobuf messages are marshalled. It is read before got is
+// marshalled, so applying it to the document does nothing.
+type protoJSONOption struct {
+	configure func(o *protojson.MarshalOptions)
+}
+
+func (p protoJSONOption) Apply(*Document, string) error {
+	return nil
+}
+
+func (p protoJSONOption) IsType() OptionType {
+	return OptionTypeConfig
+}
+
+// marshalProto marshals the protobuf message with protojson, configured by the options, to indented JSON. This is the
+// canonical JSON representation of the message, as seen by gRPC-gateway clients.
+func marshalProto(msg proto.Message, opts []Option) ([]byte, error) {
+	var marshalOpts protojson.MarshalOptions
+	for _, opt := range opts {
+		if o, ok := opt.(protoJSONOption); ok {
+			o.configure(&marshalOpts)
+		}
+	}
+	data, err := marshalOpts.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	// protojson deliberately varies its whitespace between runs, so the output is indented the same way as other
+	// values to keep the golden file stable.
+	return indentJSON(data)
+}
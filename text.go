@@ -0,0 +1,378 @@
+package golden
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// AssertText compares got with the text golden file at want, e.g. CLI output, generated code, SQL migrations or email
+// templates. If they are not equal, the test is marked as failed, but execution continues, and the failure shows a
+// unified diff of the lines.
+//
+// Use WithSkippedPatterns to skip non-deterministic parts of the text, and WithNormalizedLineEndings to ignore
+// differences in line endings.
+//
+// To update the golden file with the actual value instead of comparing with it, set the environment variable
+// "UPDATE_GOLDENS" to "1" when running the tests.
+//
+// Example: AssertText(t, "testdata/help.txt", stdout.String())
+func AssertText(t testing.TB, want string, got string, opts ...Option) {
+	t.Helper()
+	compareGolden(t, false, want, &Document{result: []byte(got)}, textFormat, append(opts, envOptions(t, want)...)...)
+}
+
+// RequireText is like AssertText, but if the golden file and got are not equal, the test is marked as failed and
+// execution stops.
+func RequireText(t testing.TB, want string, got string, opts ...Option) {
+	t.Helper()
+	compareGolden(t, true, want, &Document{result: []byte(got)}, textFormat, append(opts, envOptions(t, want)...)...)
+}
+
+// AssertBytes compares got with the binary golden file at want, e.g. images or archives. If they are not equal, the
+// test is marked as failed, but execution continues, and the failure shows where the bytes first differ.
+//
+// To update the golden file with the actual value instead of comparing with it, set the environment variable
+// "UPDATE_GOLDENS" to "1" when running the tests.
+//
+// Example: AssertBytes(t, "testdata/thumbnail.png", thumbnail)
+func AssertBytes(t testing.TB, want string, got []byte, opts ...Option) {
+	t.Helper()
+	compareGolden(t, false, want, &Document{result: got}, bytesFormat, append(opts, envOptions(t, want)...)...)
+}
+
+// RequireBytes is like AssertBytes, but if the golden file and got are not equal, the test is marked as failed and
+// execution stops.
+func RequireBytes(t testing.TB, want string, got []byte, opts ...Option) {
+	t.Helper()
+	compareGolden(t, true, want, &Document{result: got}, bytesFormat, append(opts, envOptions(t, want)...)...)
+}
+
+// textFormat is the format of text golden files.
+var textFormat = goldenFormat{
+	render:        renderUnchanged,
+	mergeComments: mergeNoComments,
+	diff:          diffNothing,
+	report:        reportTextDiff,
+}
+
+// bytesFormat is the format of binary golden files.
+var bytesFormat = goldenFormat{
+	render:        renderUnchanged,
+	mergeComments: mergeNoComments,
+	diff:          diffNothing,
+	report:        reportBytesDiff,
+}
+
+// mergeNoComments returns the new document as is, for formats without comments.
+func mergeNoComments(_, newData []byte) ([]byte, []jsoncComment, error) {
+	return newData, nil, nil
+}
+
+// diffNothing reports that the documents cannot be compared structurally, for formats without structure.
+func diffNothing(_, _ []byte) ([]jsonDiff, bool) {
+	return nil, false
+}
+
+// WithSkippedPatterns replaces the matches of the regular expressions with "--* SKIPPED *--". It is the equivalent of
+// WithSkippedFields for text golden files, whose non-deterministic parts have no GJSON path, e.g. timestamps in a log.
+// If a regular expression has capture groups, only the text matched by the groups is replaced, so that the text
+// around it can be part of the expression without being skipped.
+//
+// Example: WithSkippedPatterns(`took (\d+)ms`, `[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+//
+// In the example above, "took 12ms" is replaced with "took --* SKIPPED *--ms", and all UUIDs are replaced with
+// "--* SKIPPED *--".
+//
+// NOTE! A regular expression that does not match anything fails the test, just like a path that does not exist does
+// for WithSkippedFields.
+func WithSkippedPatterns(patterns ...string) Option {
+	return skippedPatternsOption{patterns: patterns}
+}
+
+// skippedPatternsOption implements Option for replacing the matches of regular expressions with "--* SKIPPED *--".
+type skippedPatternsOption struct {
+	patterns []string
+}
+
+func (s skippedPatternsOption) Apply(doc *Document, _ string) error {
+	var errs []error
+	for _, pattern := range s.patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			errs = append(errs, &OptionError{Reason: fmt.Sprintf("invalid pattern %q", pattern), Err: err})
+			continue
+		}
+		matches := re.FindAllSubmatchIndex(doc.result, -1)
+		if len(matches) == 0 {
+			errs = append(errs, &OptionError{Reason: fmt.Sprintf("pattern %q not found", pattern)})
+			continue
+		}
+		doc.result = replaceMatches(doc.result, matches, []byte("--* SKIPPED *--"))
+	}
+	return errors.Join(errs...)
+}
+
+func (s skippedPatternsOption) IsType() OptionType {
+	return OptionTypeModifier
+}
+
+// replaceMatches replaces the matches, as returned by regexp.FindAllSubmatchIndex, with repl. If the matches have
+// capture groups, the groups are replaced instead of the whole matches.
+func replaceMatches(data []byte, matches [][]int, repl []byte) []byte {
+	var buf bytes.Buffer
+	last := 0
+	for _, m := range matches {
+		spans := [][]int{m[:2]}
+		if len(m) > 2 {
+			spans = nil
+			for i := 2; i+1 < len(m); i += 2 {
+				// Skip groups that did not participate in the match, and groups nested in the previous one.
+				if m[i] == -1 || m[i] < last {
+					continue
+				}
+				spans = append(spans, m[i:i+2])
+			}
+		}
+		for _, span := range spans {
+			buf.Write(data[last:span[0]])
+			buf.Write(repl)
+			last = span[1]
+		}
+	}
+	buf.Write(data[last:])
+	return buf.Bytes()
+}
+
+// WithNormalizedLineEndings replaces "\r\n" and "\r" line endings with "\n" in both the golden file and the actual
+// result before they are compared. This is useful when the golden files are checked out with Windows line endings, or
+// the output depends on the operating system.
+//
+// Example: AssertText(t, "testdata/report.txt", report, WithNormalizedLineEndings())
+func WithNormalizedLineEndings() Option {
+	return normalizedLineEndingsOption{}
+}
+
+// normalizedLineEndingsOption implements Option for normalizing line endings before comparing
+type normalizedLineEndingsOption struct{}
+
+func (n normalizedLineEndingsOption) Apply(doc *Document, _ string) error {
+	doc.normalizeLineEndings = true
+	return nil
+}
+
+func (n normalizedLineEndingsOption) IsType() OptionType {
+	return OptionTypeConfig
+}
+
+// normalizeLineEndings replaces "\r\n" and "\r" line endings with "\n".
+func normalizeLineEndings(data []byte) []byte {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(data, []byte("\r"), []byte("\n"))
+}
+
+// reportTextDiff describes how the golden file and the actual result differ, as a unified diff of their lines.
+func reportTextDiff(golden, actual []byte) string {
+	diff := unifiedDiff(splitLines(string(golden)), splitLines(string(actual)))
+	if bytes.Equal(normalizeLineEndings(golden), normalizeLineEndings(actual)) {
+		return "the golden file and the actual result only differ in line endings, use WithNormalizedLineEndings to " +
+			"ignore them\n" + diff
+	}
+	return diff
+}
+
+// splitLines splits the text after each "\n", so that every line but the last one ends with "\n".
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// lineOp is a line of a line diff, with its kind: ' ' for a line in both texts, '-' for a line in the old text only,
+// and '+' for a line in the new text only.
+type lineOp struct {
+	kind byte
+	line string
+}
+
+// maxLCSCells limits the size of the table used to find the longest common subsequence of lines, so that diffing
+// large, completely different texts does not run out of memory.
+const maxLCSCells = 1 << 22
+
+// diffLines returns the line diff of the old and new lines, based on their longest common subsequence.
+func diffLines(oldLines, newLines []string) []lineOp {
+	// The common prefix and suffix are kept out of the table, since most changes are small.
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+
+	var ops []lineOp
+	for _, line := range oldLines[:prefix] {
+		ops = append(ops, lineOp{kind: ' ', line: line})
+	}
+	a := oldLines[prefix : len(oldLines)-suffix]
+	b := newLines[prefix : len(newLines)-suffix]
+	if len(a)*len(b) > maxLCSCells {
+		for _, line := range a {
+			ops = append(ops, lineOp{kind: '-', line: line})
+		}
+		for _, line := range b {
+			ops = append(ops, lineOp{kind: '+', line: line})
+		}
+	} else {
+		// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+		lcs := make([][]int, len(a)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(b)+1)
+		}
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				if a[i] == b[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(a) || j < len(b) {
+			switch {
+			case i < len(a) && j < len(b) && a[i] == b[j]:
+				ops = append(ops, lineOp{kind: ' ', line: a[i]})
+				i++
+				j++
+			case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+				ops = append(ops, lineOp{kind: '-', line: a[i]})
+				i++
+			default:
+				ops = append(ops, lineOp{kind: '+', line: b[j]})
+				j++
+			}
+		}
+	}
+	for _, line := range oldLines[len(oldLines)-suffix:] {
+		ops = append(ops, lineOp{kind: ' ', line: line})
+	}
+	return ops
+}
+
+// diffContext is the number of unchanged lines shown around the changed lines in a unified diff.
+const diffContext = 3
+
+// unifiedDiff returns the unified diff of the lines of the golden file and the actual result.
+func unifiedDiff(golden, actual []string) string {
+	ops := diffLines(golden, actual)
+
+	var sb strings.Builder
+	sb.WriteString("--- golden file\n+++ actual result")
+	for start := 0; start < len(ops); {
+		// Find the next change, and extend the hunk until the unchanged lines between two changes exceed the context.
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		last := first
+		for i := first; i < len(ops) && i <= last+2*diffContext; i++ {
+			if ops[i].kind != ' ' {
+				last = i
+			}
+		}
+		hunkStart := max(start, first-diffContext)
+		hunkEnd := min(len(ops), last+diffContext+1)
+
+		// Line numbers are one-based, and the start of an empty range is the line before it.
+		oldStart, newStart := 1, 1
+		for _, op := range ops[:hunkStart] {
+			if op.kind != '+' {
+				oldStart++
+			}
+			if op.kind != '-' {
+				newStart++
+			}
+		}
+		var oldCount, newCount int
+		for _, op := range ops[hunkStart:hunkEnd] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+		if oldCount == 0 {
+			oldStart--
+		}
+		if newCount == 0 {
+			newStart--
+		}
+		fmt.Fprintf(&sb, "\n@@ -%d,%d +%d,%d @@", oldStart, oldCount, newStart, newCount)
+		for _, op := range ops[hunkStart:hunkEnd] {
+			sb.WriteString("\n" + string(op.kind) + formatDiffLine(op.line))
+		}
+		start = hunkEnd
+	}
+	return sb.String()
+}
+
+// formatDiffLine formats a line of a unified diff. A carriage return is shown as "\r", so that differences in line
+// endings are visible, and a missing newline at the end of the text is marked.
+func formatDiffLine(line string) string {
+	text, hasNewline := strings.CutSuffix(line, "\n")
+	if cut, ok := strings.CutSuffix(text, "\r"); ok {
+		text = cut + `\r`
+	}
+	if !hasNewline {
+		text += "\n\\ No newline at end of file"
+	}
+	return text
+}
+
+// reportBytesDiff describes how the golden file and the actual result differ, with a hex dump of both around the
+// first difference.
+func reportBytesDiff(golden, actual []byte) string {
+	offset := 0
+	for offset < len(golden) && offset < len(actual) && golden[offset] == actual[offset] {
+		offset++
+	}
+	// Show the row of the first difference, and the rows before and after it.
+	start := max(0, offset/16*16-16)
+	end := start + 48
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "golden file is %d bytes, actual result is %d bytes, first difference at offset %d (0x%x)",
+		len(golden), len(actual), offset, offset)
+	sb.WriteString("\ngolden file:")
+	writeHexRows(&sb, golden, start, end)
+	sb.WriteString("\nactual result:")
+	writeHexRows(&sb, actual, start, end)
+	return sb.String()
+}
+
+// writeHexRows writes a hex dump of the data from start to end, 16 bytes per row.
+func writeHexRows(sb *strings.Builder, data []byte, start, end int) {
+	for offset := start; offset < min(end, len(data)); offset += 16 {
+		row := data[offset:min(offset+16, len(data))]
+		printable := make([]byte, len(row))
+		for i, c := range row {
+			printable[i] = '.'
+			if c >= ' ' && c <= '~' {
+				printable[i] = c
+			}
+		}
+		fmt.Fprintf(sb, "\n    %08x  %-47s  |%s|", offset, fmt.Sprintf("% x", row), printable)
+	}
+}
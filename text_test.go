@@ -0,0 +1,295 @@
+package golden
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssertText(t *testing.T) {
+	type args struct {
+		t       *testing.T
+		want    string
+		got     string
+		options []Option
+	}
+	type given struct {
+		args args
+	}
+	type test struct {
+		name  string
+		given given
+	}
+	tests := []test{
+		{
+			name: "passes when the golden file equals got",
+			given: given{
+				args: args{
+					want: "testdata/assert_text/usage.txt",
+					got:  "Usage: golden [flags]\n\nFlags:\n  -h, --help   help for golden\n",
+				},
+			},
+		},
+		{
+			name: "passes when the skipped patterns are replaced",
+			given: given{
+				args: args{
+					want: "testdata/assert_text/skipped_patterns.txt",
+					got:  "request 3f2b8c1e-6a4d-4c1b-9e2f-1a2b3c4d5e6f took 12ms\nrequest 9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d took 7ms\n",
+					options: []Option{WithSkippedPatterns(
+						`took (\d+)ms`,
+						`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`,
+					)},
+				},
+			},
+		},
+		{
+			name: "passes when only the line endings differ and they are normalized",
+			given: given{
+				args: args{
+					want:    "testdata/assert_text/windows_line_endings.txt",
+					got:     "first line\nsecond line\n",
+					options: []Option{WithNormalizedLineEndings()},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- Given --------------------------------- */
+			tt.given.args.t = &testing.T{} // test result recorder
+
+			/* ---------------------------------- When ---------------------------------- */
+			AssertText(tt.given.args.t, tt.given.args.want, tt.given.args.got, tt.given.args.options...)
+
+			/* ---------------------------------- Then ---------------------------------- */
+			require.False(t, tt.given.args.t.Failed())
+		})
+	}
+}
+
+func TestAssertText_Failure(t *testing.T) {
+	type args struct {
+		want    string
+		got     string
+		options []Option
+	}
+	type given struct {
+		args args
+	}
+	type want struct {
+		// failure is a substring of the failure report
+		failure string
+	}
+	type test struct {
+		name  string
+		given given
+		want  want
+	}
+	tests := []test{
+		{
+			name: "fails with a unified diff when the golden file is different from got",
+			given: given{
+				args: args{
+					want: "testdata/assert_text/usage.txt",
+					got:  "Usage: golden [flags]\n\nFlags:\n  -h, --help      help for golden\n  -v, --verbose   verbose output\n",
+				},
+			},
+			want: want{failure: "+  -v, --verbose   verbose output"},
+		},
+		{
+			name: "fails with a hint when only the line endings differ",
+			given: given{
+				args: args{
+					want: "testdata/assert_text/windows_line_endings.txt",
+					got:  "first line\nsecond line\n",
+				},
+			},
+			want: want{failure: "only differ in line endings, use WithNormalizedLineEndings"},
+		},
+		{
+			name: "fails when a skipped pattern is not found",
+			given: given{
+				args: args{
+					want:    "testdata/assert_text/usage.txt",
+					got:     "Usage: golden [flags]\n\nFlags:\n  -h, --help   help for golden\n",
+					options: []Option{WithSkippedPatterns(`took (\d+)ms`)},
+				},
+			},
+			want: want{failure: `pattern "took (\\d+)ms" not found`},
+		},
+		{
+			name: "fails when a skipped pattern is invalid",
+			given: given{
+				args: args{
+					want:    "testdata/assert_text/usage.txt",
+					got:     "Usage: golden [flags]\n\nFlags:\n  -h, --help   help for golden\n",
+					options: []Option{WithSkippedPatterns(`took (\d+ms`)},
+				},
+			},
+			want: want{failure: `invalid pattern "took (\\d+ms"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- Given --------------------------------- */
+			tb := newFakeTB(t.Name()) // test result recorder
+
+			/* ---------------------------------- When ---------------------------------- */
+			AssertText(tb, tt.given.args.want, tt.given.args.got, tt.given.args.options...)
+
+			/* ---------------------------------- Then ---------------------------------- */
+			require.True(t, tb.Failed())
+			require.NotEmpty(t, tb.errors)
+			require.Contains(t, tb.errors[0], tt.want.failure)
+		})
+	}
+}
+
+func TestAssertText_UpdateFlag(t *testing.T) {
+	/* ---------------------------------- Given --------------------------------- */
+	want := filepath.Join(t.TempDir(), "overwrites.txt")
+	writeFile(t, want, readFile(t, "testdata/assert_text_update_flag/overwrites.txt"))
+	tb := newFakeTB(t.Name()) // test result recorder
+
+	/* ---------------------------------- When ---------------------------------- */
+	AssertText(tb, want, "new content\n", UpdateGoldenFiles())
+	passedFirst := !tb.Failed()
+	AssertText(tb, want, "newer content\n", UpdateGoldenFiles())
+
+	/* ---------------------------------- Then ---------------------------------- */
+	require.True(t, passedFirst, "errors: %v", tb.errors)
+	require.Equal(t, "new content\n", string(readFile(t, want)), "golden file should only be written once")
+	require.True(t, tb.Failed())
+	require.Contains(t, tb.errors[0], "attempting to write to the same file twice")
+}
+
+func TestAssertBytes(t *testing.T) {
+	type args struct {
+		want string
+		got  []byte
+	}
+	type given struct {
+		args args
+	}
+	type want struct {
+		// failure is a substring of the failure report, empty if the test should pass
+		failure string
+	}
+	type test struct {
+		name  string
+		given given
+		want  want
+	}
+	tests := []test{
+		{
+			name: "passes when the golden file equals got",
+			given: given{
+				args: args{
+					want: "testdata/assert_bytes/data.bin",
+					got:  []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0x00, 0x00, 0x00, 0x0d},
+				},
+			},
+		},
+		{
+			name: "fails with the offset of the first difference",
+			given: given{
+				args: args{
+					want: "testdata/assert_bytes/data.bin",
+					got:  []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0x00, 0x00, 0x01, 0x0d, 0xff},
+				},
+			},
+			want: want{failure: "golden file is 12 bytes, actual result is 13 bytes, first difference at offset 10 (0xa)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- Given --------------------------------- */
+			tb := newFakeTB(t.Name()) // test result recorder
+
+			/* ---------------------------------- When ---------------------------------- */
+			AssertBytes(tb, tt.given.args.want, tt.given.args.got)
+
+			/* ---------------------------------- Then ---------------------------------- */
+			if tt.want.failure == "" {
+				require.False(t, tb.Failed(), "errors: %v", tb.errors)
+				return
+			}
+			require.True(t, tb.Failed())
+			require.Contains(t, tb.errors[0], tt.want.failure)
+		})
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	type given struct {
+		golden string
+		actual string
+	}
+	type want struct {
+		diff string
+	}
+	type test struct {
+		name  string
+		given given
+		want  want
+	}
+	tests := []test{
+		{
+			name: "splits distant changes into separate hunks",
+			given: given{
+				golden: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+				actual: "1\ntwo\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			},
+			want: want{diff: `--- golden file
++++ actual result
+@@ -1,5 +1,5 @@
+ 1
+-2
++two
+ 3
+ 4
+ 5
+@@ -9,4 +9,3 @@
+ 9
+ 10
+ 11
+-12`},
+		},
+		{
+			name: "marks a missing newline at the end of the text",
+			given: given{
+				golden: "a\nb\n",
+				actual: "a\nb",
+			},
+			want: want{diff: `--- golden file
++++ actual result
+@@ -1,2 +1,2 @@
+ a
+-b
++b
+\ No newline at end of file`},
+		},
+		{
+			name: "starts an empty range at the line before it",
+			given: given{
+				golden: "",
+				actual: "a\n",
+			},
+			want: want{diff: `--- golden file
++++ actual result
+@@ -0,0 +1,1 @@
++a`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- When ---------------------------------- */
+			got := unifiedDiff(splitLines(tt.given.golden), splitLines(tt.given.actual))
+
+			/* ---------------------------------- Then ---------------------------------- */
+			require.Equal(t, tt.want.diff, got)
+		})
+	}
+}
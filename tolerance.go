@@ -0,0 +1,157 @@
+package golden
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/tidwall/gjson"
+)
+
+// Tolerance is how much two numbers may differ and still be considered equal by WithNumericTolerance. The numbers are
+// equal if they differ by at most Absolute, or by at most Relative times the larger of their absolute values.
+//
+// Example: Tolerance{Absolute: 1e-9} for prices, and Tolerance{Relative: 1e-6} for scores of any magnitude.
+type Tolerance struct {
+	// Absolute is the largest difference allowed between the numbers.
+	Absolute float64
+	// Relative is the largest difference allowed between the numbers, relative to the larger of their absolute values.
+	Relative float64
+}
+
+// equal reports whether the numbers are equal within the tolerance.
+func (tol Tolerance) equal(a, b float64) bool {
+	diff := math.Abs(a - b)
+	return diff <= tol.Absolute || diff <= tol.Relative*math.Max(math.Abs(a), math.Abs(b))
+}
+
+// WithNumericTolerance compares the numbers at the paths with the numbers at the same paths in the golden file within
+// the tolerance, instead of exactly. This is useful for floats that differ in their last digits between architectures
+// or library versions, e.g. prices and scores calculated with floating-point arithmetic. Numbers within objects and
+// arrays at the paths are compared too, and without paths, all numbers in the document are, including a document
+// that is a single number.
+// The paths are GJSON paths, and may have wildcards, e.g. "items.#.price".
+// See https://github.com/tidwall/gjson/blob/master/SYNTAX.md
+//
+// A number that is equal to the golden file's number within the tolerance is replaced with it, so the comparison
+// passes, and updating the golden file keeps its numbers instead of churning their last digits. A number that is not
+// equal is reported as changed, like without the option. Pass the option after options that move or replace values,
+// e.g. WithUnorderedArrays, so that the paths match those of the golden file.
+//
+// Example: WithNumericTolerance(Tolerance{Relative: 1e-9}, "items.#.price", "total")
+//
+// NOTE! Numeric tolerance is not supported for XML golden files.
+func WithNumericTolerance(tol Tolerance, paths ...string) Option {
+	return numericToleranceOption{tolerance: tol, paths: paths}
+}
+
+// numericToleranceOption implements Option for comparing numbers within a tolerance
+type numericToleranceOption struct {
+	tolerance Tolerance
+	// paths are the GJSON paths of the numbers to compare within the tolerance. If empty, all numbers are.
+	paths []string
+}
+
+func (n numericToleranceOption) Apply(doc *Document, path string) error {
+	if doc.xml != nil {
+		return &OptionError{Reason: "numeric tolerance is not supported for XML golden files"}
+	}
+
+	var errs []error
+	roots := []string{""}
+	if len(n.paths) > 0 {
+		roots = nil
+		for _, p := range n.paths {
+			expPaths := doc.ExpandPath(p)
+			if len(expPaths) == 0 {
+				errs = append(errs, &OptionError{Path: p, Reason: "path not found"})
+			}
+			roots = append(roots, expPaths...)
+		}
+	}
+
+	// Without a golden file, or one that cannot be parsed, there are no numbers to be within the tolerance of.
+	golden, ok := readGoldenJSON(path)
+	if !ok {
+		return errors.Join(errs...)
+	}
+
+	for _, root := range roots {
+		if root == rootPath("") {
+			root = ""
+		}
+		value := gjson.ParseBytes(doc.result)
+		if root != "" {
+			value = doc.Get(root)
+		}
+		if !value.Exists() {
+			errs = append(errs, &OptionError{Path: root, Reason: "path not found"})
+			continue
+		}
+		tolerated := make(map[string]string)
+		n.tolerateNumbers(value, root, golden, tolerated)
+		for valuePath, raw := range tolerated {
+			// sjson cannot set the top-level value, so a tolerated top-level number replaces the whole document.
+			if valuePath == "" {
+				doc.SetBytes([]byte(raw))
+				continue
+			}
+			if err := doc.SetRaw(valuePath, []byte(raw)); err != nil {
+				errs = append(errs, &OptionError{Path: valuePath, Reason: "setting field value", Err: err})
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (n numericToleranceOption) IsType() OptionType {
+	return OptionTypeModifier
+}
+
+// tolerateNumbers adds the golden file's numbers that the numbers of the value at path, and of its objects and arrays,
+// are equal to within the tolerance to tolerated by their concrete paths. Numbers that are already the same are left
+// out.
+func (n numericToleranceOption) tolerateNumbers(value gjson.Result, path string, golden []byte,
+	tolerated map[string]string,
+) {
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+	switch {
+	case value.Type == gjson.Number:
+		want := gjson.ParseBytes(golden)
+		if path != "" {
+			want = gjson.GetBytes(golden, path)
+		}
+		if want.Type == gjson.Number && want.Raw != value.Raw && n.tolerance.equal(want.Num, value.Num) {
+			tolerated[path] = want.Raw
+		}
+	case value.IsArray():
+		for i, elem := range value.Array() {
+			n.tolerateNumbers(elem, join(fmt.Sprint(i)), golden, tolerated)
+		}
+	case value.IsObject():
+		value.ForEach(func(key, elem gjson.Result) bool {
+			n.tolerateNumbers(elem, join(escapePathKey(key.Str)), golden, tolerated)
+			return true
+		})
+	}
+}
+
+// readGoldenJSON returns the JSON of the golden file at path, without comments. YAML golden files are converted to
+// JSON. It returns false if the golden file does not exist or cannot be parsed.
+func readGoldenJSON(path string) ([]byte, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	if stripped := stripJSONComments(data); gjson.ValidBytes(stripped) {
+		return stripped, true
+	}
+	data, err = yamlToJSON(data)
+	return data, err == nil
+}
@@ -0,0 +1,149 @@
+package golden
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// pricing is a response of a pricing endpoint, whose floats differ in their last digits from those in
+// testdata/assert_json/numeric_tolerance.json.
+const pricing = `{
+    "items": [
+        {
+            "sku": "X-1",
+            "price": 19.990000000000002,
+            "quantity": 2
+        }
+    ],
+    "total": 39.980000000000004,
+    "score": 0.1234568
+}`
+
+func TestWithNumericTolerance(t *testing.T) {
+	type args struct {
+		want    string
+		got     any
+		options []Option
+	}
+	type given struct {
+		args args
+	}
+	type want struct {
+		// failures are the failures reported, see requireFailures. If empty, the test should pass.
+		failures []string
+	}
+	type test struct {
+		name  string
+		given given
+		want  want
+	}
+	tests := []test{
+		{
+			name: "passes when the numbers at the paths are within the tolerance",
+			given: given{
+				args: args{
+					want: "testdata/assert_json/numeric_tolerance.json",
+					got:  RawJSON(pricing),
+					options: []Option{
+						WithNumericTolerance(Tolerance{Absolute: 1e-9}, "items.#.price", "total"),
+						WithNumericTolerance(Tolerance{Relative: 1e-6}, "score"),
+					},
+				},
+			},
+		},
+		{
+			name: "passes when all numbers are within the tolerance",
+			given: given{
+				args: args{
+					want:    "testdata/assert_json/numeric_tolerance.json",
+					got:     RawJSON(pricing),
+					options: []Option{WithNumericTolerance(Tolerance{Relative: 1e-6})},
+				},
+			},
+		},
+		{
+			name: "passes when the top-level number is within the tolerance",
+			given: given{
+				args: args{
+					want:    "testdata/assert_json/numeric_tolerance_top_level.json",
+					got:     0.1234568,
+					options: []Option{WithNumericTolerance(Tolerance{Relative: 1e-3})},
+				},
+			},
+		},
+		{
+			name: "passes when the number at @this is within the tolerance",
+			given: given{
+				args: args{
+					want:    "testdata/assert_json/numeric_tolerance_top_level.json",
+					got:     0.1234568,
+					options: []Option{WithNumericTolerance(Tolerance{Relative: 1e-3}, "@this")},
+				},
+			},
+		},
+		{
+			name: "fails when a number is not within the tolerance",
+			given: given{
+				args: args{
+					want:    "testdata/assert_json/numeric_tolerance.json",
+					got:     RawJSON(pricing),
+					options: []Option{WithNumericTolerance(Tolerance{Absolute: 1e-9})},
+				},
+			},
+			want: want{failures: []string{`changed      score: 0.123456789 => 0.1234568`}},
+		},
+		{
+			name: "fails when the numbers at paths without tolerance are different",
+			given: given{
+				args: args{
+					want:    "testdata/assert_json/numeric_tolerance.json",
+					got:     RawJSON(pricing),
+					options: []Option{WithNumericTolerance(Tolerance{Relative: 1e-6}, "total", "score")},
+				},
+			},
+			want: want{failures: []string{`changed      items.0.price: 19.99 => 19.990000000000002`}},
+		},
+		{
+			name: "fails when the path does not exist",
+			given: given{
+				args: args{
+					want: "testdata/assert_json/numeric_tolerance.json",
+					got:  RawJSON(pricing),
+					options: []Option{
+						WithNumericTolerance(Tolerance{Relative: 1e-6}, "items.#.price", "total", "score", "discount"),
+					},
+				},
+			},
+			want: want{failures: []string{"path = discount: path not found"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- Given --------------------------------- */
+			tb := newFakeTB(t.Name()) // test result recorder
+
+			/* ---------------------------------- When ---------------------------------- */
+			AssertJSON(tb, tt.given.args.want, tt.given.args.got, tt.given.args.options...)
+
+			/* ---------------------------------- Then ---------------------------------- */
+			requireFailures(t, tb, tt.want.failures...)
+		})
+	}
+}
+
+func TestWithNumericTolerance_UpdateFlag(t *testing.T) {
+	/* ---------------------------------- Given --------------------------------- */
+	initial := readFile(t, "testdata/assert_json/numeric_tolerance.json")
+	want := filepath.Join(t.TempDir(), "numeric_tolerance.json")
+	writeFile(t, want, initial)
+	tb := newFakeTB(t.Name()) // test result recorder
+
+	/* ---------------------------------- When ---------------------------------- */
+	AssertJSON(tb, want, RawJSON(pricing), UpdateGoldenFiles(), WithNumericTolerance(Tolerance{Relative: 1e-6}))
+
+	/* ---------------------------------- Then ---------------------------------- */
+	requireFailures(t, tb)
+	require.Equal(t, string(initial), string(readFile(t, want)), "numbers within the tolerance should not be updated")
+}
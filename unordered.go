@@ -0,0 +1,149 @@
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// WithUnorderedArrays sorts the elements of the arrays at the paths, so that their order does not matter. This is
+// useful for arrays whose order is not deterministic, e.g. rows from a SQL query without ORDER BY, or values collected
+// by iterating over a map. The arrays are sorted both before they are compared and before the golden file is written,
+// so the golden file is sorted too.
+// The paths are GJSON paths, and may have wildcards, e.g. "users.#.roles". The path "@this" is the top-level array,
+// e.g. the rows returned by a list endpoint.
+// See https://github.com/tidwall/gjson/blob/master/SYNTAX.md
+//
+// The elements are sorted by their canonical JSON, i.e. compact JSON with the keys of objects sorted. Arrays nested
+// in others are sorted first, so the order of the outer array doesn't depend on the order of the inner ones. Use
+// WithUnorderedArraysBy to sort objects by one of their fields instead, which keeps related objects close in the
+// golden file.
+//
+// Example: WithUnorderedArrays("tags", "users.#.roles")
+//
+// NOTE! Unordered arrays are not supported for XML golden files.
+func WithUnorderedArrays(paths ...string) Option {
+	return unorderedArraysOption{paths: paths}
+}
+
+// WithUnorderedArraysBy is like WithUnorderedArrays, but sorts the elements by the value at the key, a GJSON path
+// relative to the element, e.g. "id". Numbers are sorted numerically and strings lexically. Elements with equal keys
+// are sorted by their canonical JSON.
+//
+// Example: WithUnorderedArraysBy("id", "users")
+func WithUnorderedArraysBy(key string, paths ...string) Option {
+	return unorderedArraysOption{key: key, paths: paths}
+}
+
+// unorderedArraysOption implements Option for sorting arrays whose order does not matter
+type unorderedArraysOption struct {
+	// key is the GJSON path, relative to the elements, of the values the elements are sorted by. If empty, they are
+	// sorted by their canonical JSON.
+	key   string
+	paths []string
+}
+
+func (u unorderedArraysOption) Apply(doc *Document, _ string) error {
+	if doc.xml != nil {
+		return &OptionError{Reason: "unordered arrays are not supported for XML golden files"}
+	}
+
+	var errs []error
+	var expPaths []string
+	for _, path := range u.paths {
+		paths := doc.ExpandPath(path)
+		if len(paths) == 0 {
+			errs = append(errs, &OptionError{Path: path, Reason: "path not found"})
+		}
+		expPaths = append(expPaths, paths...)
+	}
+	// Sort the deepest arrays first, so that nested arrays are sorted before the arrays they are elements of.
+	sort.SliceStable(expPaths, func(i, j int) bool {
+		return strings.Count(expPaths[i], ".") > strings.Count(expPaths[j], ".")
+	})
+
+	for _, path := range expPaths {
+		res := doc.Get(path)
+		if !res.Exists() {
+			errs = append(errs, &OptionError{Path: path, Reason: "path not found"})
+			continue
+		}
+		if !res.IsArray() {
+			errs = append(errs, &OptionError{Path: path, Reason: "not an array"})
+			continue
+		}
+		// sjson cannot set the top-level value, so a sorted top-level array replaces the whole document.
+		if path == rootPath("") {
+			doc.SetBytes(sortJSONArray(res, u.key))
+			continue
+		}
+		if err := doc.SetRaw(path, sortJSONArray(res, u.key)); err != nil {
+			errs = append(errs, &OptionError{Path: path, Reason: "setting field value", Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (u unorderedArraysOption) IsType() OptionType {
+	return OptionTypeModifier
+}
+
+// sortJSONArray returns the raw JSON of the array with its elements sorted by the value at the key, or by their
+// canonical JSON if the key is empty. The whitespace between the elements is kept, so that the indentation of the
+// document is too.
+func sortJSONArray(array gjson.Result, key string) []byte {
+	elems := array.Array()
+	type element struct {
+		value     gjson.Result
+		canonical string
+	}
+	sorted := make([]element, len(elems))
+	for i, elem := range elems {
+		sorted[i] = element{value: elem, canonical: canonicalJSON(elem.Raw)}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if key != "" {
+			ki, kj := sorted[i].value.Get(key), sorted[j].value.Get(key)
+			if ki.Less(kj, true) {
+				return true
+			}
+			if kj.Less(ki, true) {
+				return false
+			}
+		}
+		return sorted[i].canonical < sorted[j].canonical
+	})
+
+	// The elements appear in the raw array in order, separated by commas and whitespace, which are kept.
+	raw := array.Raw
+	var buf bytes.Buffer
+	last := 0
+	for i, elem := range elems {
+		start := last + strings.Index(raw[last:], elem.Raw)
+		buf.WriteString(raw[last:start])
+		buf.WriteString(sorted[i].value.Raw)
+		last = start + len(elem.Raw)
+	}
+	buf.WriteString(raw[last:])
+	return buf.Bytes()
+}
+
+// canonicalJSON returns the compact JSON of the raw JSON value, with the keys of objects sorted. Numbers keep their
+// text. If the value is not valid JSON, it is returned as is.
+func canonicalJSON(raw string) string {
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return raw
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return raw
+	}
+	return string(data)
+}
@@ -0,0 +1,123 @@
+package golden
+
+import "testing"
+
+func TestWithUnorderedArrays(t *testing.T) {
+	type args struct {
+		want    string
+		got     any
+		options []Option
+	}
+	type given struct {
+		args args
+	}
+	type want struct {
+		// failures are the failures reported, see requireFailures. If empty, the test should pass.
+		failures []string
+	}
+	type test struct {
+		name  string
+		given given
+		want  want
+	}
+	tests := []test{
+		{
+			name: "sorts arrays by their canonical JSON",
+			given: given{
+				args: args{
+					want: "testdata/assert_json/unordered_arrays.json",
+					got: RawJSON(`{
+						"members": [
+							{"id": 10, "name": "Eliana", "roles": ["writer", "admin"]},
+							{"id": 2, "name": "John", "roles": ["reader"]},
+							{"id": 7, "name": "Ada", "roles": ["admin", "reader", "writer"]}
+						],
+						"tags": ["backend", 42, null, {"b": 1, "a": 2}]
+					}`),
+					options: []Option{WithUnorderedArrays("tags", "members", "members.#.roles")},
+				},
+			},
+		},
+		{
+			name: "passes when the arrays are in another order",
+			given: given{
+				args: args{
+					want: "testdata/assert_json/unordered_arrays.json",
+					got: RawJSON(`{
+						"members": [
+							{"id": 7, "name": "Ada", "roles": ["writer", "reader", "admin"]},
+							{"id": 10, "name": "Eliana", "roles": ["admin", "writer"]},
+							{"id": 2, "name": "John", "roles": ["reader"]}
+						],
+						"tags": [{"b": 1, "a": 2}, null, 42, "backend"]
+					}`),
+					options: []Option{WithUnorderedArrays("tags", "members", "members.#.roles")},
+				},
+			},
+		},
+		{
+			name: "sorts arrays by the key",
+			given: given{
+				args: args{
+					want: "testdata/assert_json/unordered_arrays_by_key.json",
+					got: RawJSON(`{
+						"members": [
+							{"id": 10, "name": "Eliana"},
+							{"id": 2, "name": "John"},
+							{"id": 7, "name": "Ada"}
+						]
+					}`),
+					options: []Option{WithUnorderedArraysBy("id", "members")},
+				},
+			},
+		},
+		{
+			name: "sorts the top-level array",
+			given: given{
+				args: args{
+					want: "testdata/assert_json/unordered_arrays_top_level.json",
+					got: RawJSON(`[
+						{"id": 10, "name": "Eliana"},
+						{"id": 2, "name": "John"},
+						{"id": 7, "name": "Ada"}
+					]`),
+					options: []Option{WithUnorderedArraysBy("id", "@this")},
+				},
+			},
+		},
+		{
+			name: "fails when the path does not exist",
+			given: given{
+				args: args{
+					want:    "testdata/assert_json/unordered_arrays_object.json",
+					got:     map[string]any{"members": map[string]any{"id": 10}},
+					options: []Option{WithUnorderedArrays("owners")},
+				},
+			},
+			want: want{failures: []string{"path = owners: path not found"}},
+		},
+		{
+			name: "fails when the value is not an array",
+			given: given{
+				args: args{
+					want:    "testdata/assert_json/unordered_arrays_object.json",
+					got:     map[string]any{"members": map[string]any{"id": 10}},
+					options: []Option{WithUnorderedArrays("members")},
+				},
+			},
+			want: want{failures: []string{"path = members: not an array"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- Given --------------------------------- */
+			tb := newFakeTB(t.Name()) // test result recorder
+
+			/* ---------------------------------- When ---------------------------------- */
+			AssertJSON(tb, tt.given.args.want, tt.given.args.got, tt.given.args.options...)
+
+			/* ---------------------------------- Then ---------------------------------- */
+			requireFailures(t, tb, tt.want.failures...)
+		})
+	}
+}
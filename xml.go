@@ -0,0 +1,888 @@
+package golden
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// AssertXML compares the XML representation of got with the XML golden file at want. If they are not equal, the test
+// is marked as failed, but execution continues.
+//
+// got is either raw XML, as a []byte or a string, or a value that is marshalled with encoding/xml. Both got and the
+// golden file are canonicalized before they are compared: the XML declaration is dropped, attributes are sorted by
+// name, whitespace between elements is ignored, and the document is indented with four spaces. So neither the order
+// of attributes nor the formatting causes a failure.
+//
+// The paths of WithSkippedFields, WithStablePlaceholders and WithFieldComments are XPath-like paths for XML golden
+// files, instead of GJSON paths. They support:
+//   - "/a/b": the b child elements of the a root element.
+//   - "//b": the b elements at any depth.
+//   - "*": any element, e.g. "/a/*/c".
+//   - "b[2]": the second b child element, counting from 1.
+//   - "b[@id='x']": the b child elements with the attribute id set to "x".
+//   - "/a/b/@id": the id attribute of the b elements.
+//
+// Element names match with or without their namespace prefix, e.g. both "/soap:Envelope" and "/Envelope" match the
+// element <soap:Envelope>. Field comments and the file comment are rendered as "<!-- comment -->".
+//
+// To update the golden file with the actual value instead of comparing with it, set the environment variable
+// "UPDATE_GOLDENS" to "1" when running the tests.
+//
+// Example: AssertXML(t, "testdata/feed.xml", feed, WithSkippedFields("/rss/channel/lastBuildDate"))
+func AssertXML(t testing.TB, want string, got any, opts ...Option) {
+	t.Helper()
+	compareXML(t, false, want, got, append(opts, envOptions(t, want)...)...)
+}
+
+// RequireXML is like AssertXML, but if the golden file and got are not equal, the test is marked as failed and
+// execution stops.
+func RequireXML(t testing.TB, want string, got any, opts ...Option) {
+	t.Helper()
+	compareXML(t, true, want, got, append(opts, envOptions(t, want)...)...)
+}
+
+func compareXML(t testing.TB, failNow bool, want string, got any, opts ...Option) {
+	t.Helper()
+
+	var gotBytes []byte
+	switch v := got.(type) {
+	case []byte:
+		gotBytes = v
+	case string:
+		gotBytes = []byte(v)
+	default:
+		var err error
+		gotBytes, err = xml.Marshal(got)
+		if !NoError(t, failNow, err, "marshalling got") {
+			return
+		}
+	}
+
+	doc, err := parseXML(gotBytes)
+	if !NoError(t, failNow, err, "parsing got") {
+		return
+	}
+
+	compareGolden(t, failNow, want, &Document{xml: doc}, xmlFormat, opts...)
+}
+
+// xmlFormat is the format of XML golden files.
+var xmlFormat = goldenFormat{
+	render:        func(doc *Document) ([]byte, error) { return renderXML(doc.xml), nil },
+	mergeComments: mergeXMLComments,
+	diff:          diffXML,
+	canonical:     true,
+}
+
+// xmlNodeKind is the kind of a node in an XML document.
+type xmlNodeKind int
+
+const (
+	xmlDocumentNode xmlNodeKind = iota
+	xmlElementNode
+	xmlTextNode
+	xmlCommentNode
+	xmlProcInstNode
+	xmlDirectiveNode
+)
+
+// xmlAttr is an attribute of an XML element.
+type xmlAttr struct {
+	name, value string
+}
+
+// xmlNode is a node in an XML document.
+type xmlNode struct {
+	kind   xmlNodeKind
+	parent *xmlNode
+	// name is the name of an element, including its namespace prefix, e.g. "soap:Body", or the target of a processing
+	// instruction.
+	name string
+	// attrs are the attributes of an element, sorted by name.
+	attrs    []xmlAttr
+	children []*xmlNode
+	// data is the text of a text or comment node, or the content of a processing instruction or directive.
+	data string
+	// trailing is true for a comment on the same line as the end of the element before it.
+	trailing bool
+	// endLine is the line where an element ends when it was parsed.
+	endLine int
+}
+
+// appendChild appends the child to the node's children.
+func (n *xmlNode) appendChild(child *xmlNode) {
+	child.parent = n
+	n.children = append(n.children, child)
+}
+
+// insertChild inserts the child at index i of the node's children.
+func (n *xmlNode) insertChild(i int, child *xmlNode) {
+	child.parent = n
+	n.children = append(n.children[:i], append([]*xmlNode{child}, n.children[i:]...)...)
+}
+
+// index returns the index of the node in its parent's children.
+func (n *xmlNode) index() int {
+	for i, c := range n.parent.children {
+		if c == n {
+			return i
+		}
+	}
+	return -1
+}
+
+// root returns the root element of the document node, or nil if it has none.
+func (n *xmlNode) root() *xmlNode {
+	for _, c := range n.children {
+		if c.kind == xmlElementNode {
+			return c
+		}
+	}
+	return nil
+}
+
+// childElements returns the child elements with the name.
+func (n *xmlNode) childElements(name string) []*xmlNode {
+	var elems []*xmlNode
+	for _, c := range n.children {
+		if c.kind == xmlElementNode && c.name == name {
+			elems = append(elems, c)
+		}
+	}
+	return elems
+}
+
+// text returns the text of the element, i.e. its text children joined with spaces.
+func (n *xmlNode) text() string {
+	var texts []string
+	for _, c := range n.children {
+		if c.kind == xmlTextNode {
+			texts = append(texts, c.data)
+		}
+	}
+	return strings.Join(texts, " ")
+}
+
+// parseXML parses an XML document. Whitespace around text is trimmed, and the XML declaration is dropped.
+func parseXML(data []byte) (*xmlNode, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	doc := &xmlNode{kind: xmlDocumentNode}
+	cur := doc
+	for {
+		startLine, _ := dec.InputPos()
+		// Raw tokens keep the namespace prefixes as written, instead of replacing them with the namespace URLs.
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			elem := &xmlNode{kind: xmlElementNode, name: xmlName(t.Name)}
+			for _, a := range t.Attr {
+				elem.attrs = append(elem.attrs, xmlAttr{name: xmlName(a.Name), value: a.Value})
+			}
+			sort.Slice(elem.attrs, func(i, j int) bool { return elem.attrs[i].name < elem.attrs[j].name })
+			cur.appendChild(elem)
+			cur = elem
+		case xml.EndElement:
+			if cur.kind != xmlElementNode || cur.name != xmlName(t.Name) {
+				return nil, fmt.Errorf("unexpected end element </%s> on line %d", xmlName(t.Name), startLine)
+			}
+			cur.endLine, _ = dec.InputPos()
+			cur = cur.parent
+		case xml.CharData:
+			if text := strings.TrimSpace(string(t)); text != "" {
+				cur.appendChild(&xmlNode{kind: xmlTextNode, data: text})
+			}
+		case xml.Comment:
+			comment := &xmlNode{kind: xmlCommentNode, data: string(t)}
+			if len(cur.children) > 0 {
+				prev := cur.children[len(cur.children)-1]
+				comment.trailing = prev.kind == xmlElementNode && prev.endLine == startLine
+			}
+			cur.appendChild(comment)
+		case xml.ProcInst:
+			if t.Target == "xml" {
+				continue
+			}
+			cur.appendChild(&xmlNode{kind: xmlProcInstNode, name: t.Target, data: string(t.Inst)})
+		case xml.Directive:
+			cur.appendChild(&xmlNode{kind: xmlDirectiveNode, data: string(t)})
+		}
+	}
+	if cur != doc {
+		return nil, fmt.Errorf("element <%s> is not closed", cur.name)
+	}
+	if doc.root() == nil {
+		return nil, errors.New("document has no root element")
+	}
+	return doc, nil
+}
+
+// xmlName returns the name including its namespace prefix, as returned by xml.Decoder.RawToken.
+func xmlName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// renderXML renders the XML document in canonical form, indented with four spaces. Comments after the root element
+// are separated from it by a blank line.
+func renderXML(doc *xmlNode) []byte {
+	var buf bytes.Buffer
+	writeXMLChildren(&buf, doc, "")
+	buf.WriteString("\n")
+	return buf.Bytes()
+}
+
+// writeXMLChildren writes the children of the node, one per line, except trailing comments.
+func writeXMLChildren(buf *bytes.Buffer, n *xmlNode, indent string) {
+	for i, c := range n.children {
+		if i > 0 {
+			if c.kind == xmlCommentNode && c.trailing {
+				buf.WriteString(" ")
+				writeXMLNode(buf, c, "")
+				continue
+			}
+			buf.WriteString("\n")
+			// A blank line separates the file comment from the root element.
+			if n.kind == xmlDocumentNode && c.kind == xmlElementNode && n.children[i-1].kind == xmlCommentNode {
+				buf.WriteString("\n")
+			}
+		}
+		writeXMLNode(buf, c, indent)
+	}
+}
+
+// writeXMLNode writes the node at the indentation.
+func writeXMLNode(buf *bytes.Buffer, n *xmlNode, indent string) {
+	buf.WriteString(indent)
+	switch n.kind {
+	case xmlTextNode:
+		buf.WriteString(xmlTextEscaper.Replace(n.data))
+	case xmlCommentNode:
+		buf.WriteString("<!--" + n.data + "-->")
+	case xmlProcInstNode:
+		buf.WriteString("<?" + n.name)
+		if n.data != "" {
+			buf.WriteString(" " + n.data)
+		}
+		buf.WriteString("?>")
+	case xmlDirectiveNode:
+		buf.WriteString("<!" + n.data + ">")
+	case xmlElementNode:
+		buf.WriteString("<" + n.name)
+		for _, a := range n.attrs {
+			buf.WriteString(" " + a.name + `="` + xmlAttrEscaper.Replace(a.value) + `"`)
+		}
+		switch {
+		case len(n.children) == 0:
+			buf.WriteString("/>")
+		case len(n.children) == 1 && n.children[0].kind == xmlTextNode:
+			buf.WriteString(">" + xmlTextEscaper.Replace(n.children[0].data) + "</" + n.name + ">")
+		default:
+			buf.WriteString(">\n")
+			writeXMLChildren(buf, n, indent+"    ")
+			buf.WriteString("\n" + indent + "</" + n.name + ">")
+		}
+	}
+}
+
+var (
+	// xmlTextEscaper escapes the characters that cannot be used in XML text.
+	xmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	// xmlAttrEscaper escapes the characters that cannot be used in XML attribute values. Whitespace characters are
+	// escaped so that they are not normalized to spaces when parsed.
+	xmlAttrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "\n", "&#xA;",
+		"\r", "&#xD;", "\t", "&#x9;")
+)
+
+// compactXML renders the element on a single line without comments, for diff reports.
+func compactXML(n *xmlNode) string {
+	var sb strings.Builder
+	sb.WriteString("<" + n.name)
+	for _, a := range n.attrs {
+		sb.WriteString(" " + a.name + `="` + xmlAttrEscaper.Replace(a.value) + `"`)
+	}
+	sb.WriteString(">")
+	for _, c := range n.children {
+		switch c.kind {
+		case xmlElementNode:
+			sb.WriteString(compactXML(c))
+		case xmlTextNode:
+			sb.WriteString(xmlTextEscaper.Replace(c.data))
+		}
+	}
+	sb.WriteString("</" + n.name + ">")
+	return sb.String()
+}
+
+// xmlComment returns the comment text with every "--", which must not be used in XML comments, replaced.
+func xmlComment(text string) string {
+	for strings.Contains(text, "--") {
+		text = strings.ReplaceAll(text, "--", "- -")
+	}
+	return text
+}
+
+// xmlPaths returns the canonical XPath-like paths of all elements in the document, e.g. "/rss/channel/item[2]". The
+// position is only included when an element has siblings with the same name.
+func xmlPaths(doc *xmlNode) map[*xmlNode]string {
+	paths := map[*xmlNode]string{doc: ""}
+	var walk func(n *xmlNode)
+	walk = func(n *xmlNode) {
+		counts := make(map[string]int)
+		for _, c := range n.children {
+			if c.kind == xmlElementNode {
+				counts[c.name]++
+			}
+		}
+		positions := make(map[string]int)
+		for _, c := range n.children {
+			if c.kind != xmlElementNode {
+				continue
+			}
+			positions[c.name]++
+			step := c.name
+			if counts[c.name] > 1 {
+				step += "[" + strconv.Itoa(positions[c.name]) + "]"
+			}
+			paths[c] = paths[n] + "/" + step
+			walk(c)
+		}
+	}
+	walk(doc)
+	return paths
+}
+
+// xpathStep is a step of an XPath-like path.
+type xpathStep struct {
+	// descendant is true for a step after "//", which matches elements at any depth.
+	descendant bool
+	// name is the name of the element or attribute, or "*" for any name.
+	name string
+	// attr is true when the step matches attributes instead of elements.
+	attr bool
+	// predicates filter the matching elements, in order.
+	predicates []xpathPredicate
+}
+
+// xpathPredicate filters the elements matched by a step, either by their position or by the value of an attribute.
+type xpathPredicate struct {
+	// position is the one-based position of the element among the matching siblings, or 0.
+	position int
+	// attrName and attrValue are the attribute the elements must have, when position is 0.
+	attrName, attrValue string
+}
+
+// parseXPath parses an XPath-like path into its steps.
+func parseXPath(path string) ([]xpathStep, error) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, errors.New("path must start with /")
+	}
+	var steps []xpathStep
+	for i := 0; i < len(path); {
+		var step xpathStep
+		if strings.HasPrefix(path[i:], "//") {
+			step.descendant = true
+			i += 2
+		} else {
+			i++
+		}
+		start := i
+		for i < len(path) && path[i] != '/' && path[i] != '[' {
+			i++
+		}
+		step.name = path[start:i]
+		if name, ok := strings.CutPrefix(step.name, "@"); ok {
+			step.attr, step.name = true, name
+		}
+		if step.name == "" {
+			return nil, fmt.Errorf("empty step at offset %d", start)
+		}
+		for i < len(path) && path[i] == '[' {
+			end := i + 1
+			var quote byte
+			for ; end < len(path) && (quote != 0 || path[end] != ']'); end++ {
+				switch {
+				case quote == 0 && (path[end] == '\'' || path[end] == '"'):
+					quote = path[end]
+				case quote == path[end]:
+					quote = 0
+				}
+			}
+			if end == len(path) {
+				return nil, fmt.Errorf("unterminated predicate at offset %d", i)
+			}
+			pred, err := parseXPathPredicate(path[i+1 : end])
+			if err != nil {
+				return nil, err
+			}
+			step.predicates = append(step.predicates, pred)
+			i = end + 1
+		}
+		if step.attr && (len(step.predicates) > 0 || i < len(path)) {
+			return nil, errors.New("an attribute step must be the last step, without predicates")
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// parseXPathPredicate parses the predicate between the brackets, e.g. "2" or "@id='x'".
+func parseXPathPredicate(pred string) (xpathPredicate, error) {
+	if position, err := strconv.Atoi(pred); err == nil && position > 0 {
+		return xpathPredicate{position: position}, nil
+	}
+	name, value, ok := strings.Cut(pred, "=")
+	name, hasAt := strings.CutPrefix(strings.TrimSpace(name), "@")
+	value = strings.TrimSpace(value)
+	if !ok || !hasAt || len(value) < 2 || (value[0] != '\'' && value[0] != '"') || value[len(value)-1] != value[0] {
+		return xpathPredicate{}, fmt.Errorf("unsupported predicate [%s], use a position or [@name='value']", pred)
+	}
+	return xpathPredicate{attrName: name, attrValue: value[1 : len(value)-1]}, nil
+}
+
+// matchXMLName reports whether the name matches the name test of a step. A name test without a namespace prefix
+// matches names with any prefix.
+func matchXMLName(test, name string) bool {
+	if test == "*" || test == name {
+		return true
+	}
+	_, local, ok := strings.Cut(name, ":")
+	return ok && !strings.Contains(test, ":") && test == local
+}
+
+// xmlMatch is an element or attribute matched by an XPath-like path.
+type xmlMatch struct {
+	elem *xmlNode
+	// attr is the index of the matched attribute in elem.attrs, or -1 when the element itself matched.
+	attr int
+}
+
+// evalXPath returns the elements or attributes in the document that match the XPath-like path, in document order.
+func evalXPath(doc *xmlNode, path string) ([]xmlMatch, error) {
+	steps, err := parseXPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	current := []*xmlNode{doc}
+	for _, step := range steps {
+		if step.attr {
+			var matches []xmlMatch
+			for _, elem := range current {
+				for i, a := range elem.attrs {
+					if matchXMLName(step.name, a.name) {
+						matches = append(matches, xmlMatch{elem: elem, attr: i})
+					}
+				}
+			}
+			return matches, nil
+		}
+
+		var next []*xmlNode
+		seen := make(map[*xmlNode]bool)
+		for _, n := range current {
+			parents := []*xmlNode{n}
+			if step.descendant {
+				parents = xmlDescendantsOrSelf(n)
+			}
+			for _, parent := range parents {
+				for _, elem := range step.filter(parent) {
+					if !seen[elem] {
+						seen[elem] = true
+						next = append(next, elem)
+					}
+				}
+			}
+		}
+		current = next
+	}
+
+	matches := make([]xmlMatch, len(current))
+	for i, elem := range current {
+		matches[i] = xmlMatch{elem: elem, attr: -1}
+	}
+	return matches, nil
+}
+
+// filter returns the child elements of the parent that match the step.
+func (s xpathStep) filter(parent *xmlNode) []*xmlNode {
+	var elems []*xmlNode
+	for _, c := range parent.children {
+		if c.kind == xmlElementNode && matchXMLName(s.name, c.name) {
+			elems = append(elems, c)
+		}
+	}
+	for _, pred := range s.predicates {
+		if pred.position > 0 {
+			if pred.position > len(elems) {
+				return nil
+			}
+			elems = elems[pred.position-1 : pred.position]
+			continue
+		}
+		var filtered []*xmlNode
+		for _, elem := range elems {
+			for _, a := range elem.attrs {
+				if matchXMLName(pred.attrName, a.name) && a.value == pred.attrValue {
+					filtered = append(filtered, elem)
+					break
+				}
+			}
+		}
+		elems = filtered
+	}
+	return elems
+}
+
+// xmlDescendantsOrSelf returns the node and all elements below it, in document order.
+func xmlDescendantsOrSelf(n *xmlNode) []*xmlNode {
+	nodes := []*xmlNode{n}
+	for _, c := range n.children {
+		if c.kind == xmlElementNode {
+			nodes = append(nodes, xmlDescendantsOrSelf(c)...)
+		}
+	}
+	return nodes
+}
+
+// skipXMLNodes replaces the content of the elements, or the values of the attributes, matching the XPath-like path
+// with "--* SKIPPED *--". If keepNull is true, empty elements and attributes are left untouched.
+func skipXMLNodes(doc *xmlNode, path string, keepNull bool) error {
+	matches, err := evalXPath(doc, path)
+	if err != nil {
+		return &OptionError{Path: path, Reason: "invalid path", Err: err}
+	}
+	if len(matches) == 0 {
+		return &OptionError{Path: path, Reason: "path not found"}
+	}
+	for _, m := range matches {
+		if m.attr >= 0 {
+			if keepNull && m.elem.attrs[m.attr].value == "" {
+				continue
+			}
+			m.elem.attrs[m.attr].value = "--* SKIPPED *--"
+			continue
+		}
+		if keepNull && len(m.elem.children) == 0 {
+			continue
+		}
+		m.elem.children = nil
+		m.elem.appendChild(&xmlNode{kind: xmlTextNode, data: "--* SKIPPED *--"})
+	}
+	return nil
+}
+
+// placeholdXMLNodes replaces the values of the elements and attributes matching the XPath-like path with the
+// placeholders of their values. Elements are matched by their text, and empty values are left untouched.
+func placeholdXMLNodes(doc *xmlNode, path string, placeholder func(value string) string) error {
+	matches, err := evalXPath(doc, path)
+	if err != nil {
+		return &OptionError{Path: path, Reason: "invalid path", Err: err}
+	}
+	if len(matches) == 0 {
+		return &OptionError{Path: path, Reason: "path not found"}
+	}
+	for _, m := range matches {
+		if m.attr >= 0 {
+			if value := m.elem.attrs[m.attr].value; value != "" {
+				m.elem.attrs[m.attr].value = placeholder(value)
+			}
+			continue
+		}
+		if len(m.elem.children) == 0 {
+			continue
+		}
+		value := m.elem.text()
+		m.elem.children = nil
+		m.elem.appendChild(&xmlNode{kind: xmlTextNode, data: placeholder(value)})
+	}
+	return nil
+}
+
+// commentXMLNodes adds the comment at the end of the line of the elements matching the XPath-like path. Comments on
+// attributes are added to their elements.
+func commentXMLNodes(doc *xmlNode, path, comment string) error {
+	matches, err := evalXPath(doc, path)
+	if err != nil {
+		return &OptionError{Path: path, Reason: "invalid path", Err: err}
+	}
+	if len(matches) == 0 {
+		return &OptionError{Path: path, Reason: "path not found"}
+	}
+	for _, m := range matches {
+		insertXMLComment(m.elem, anchorTrailing, " "+xmlComment(comment)+" ")
+	}
+	return nil
+}
+
+// addXMLFileComment adds the comment before the root element of the document.
+func addXMLFileComment(doc *xmlNode, comment string) {
+	insertXMLComment(doc, anchorFile, "\n"+xmlComment(comment)+"\n")
+}
+
+// xmlCommentAnchor returns the node the comment at index i of the node's children is attached to, and how. A
+// comment describes the element it trails on the same line, or else the next element. The comments after the last
+// element describe the node itself, and those before the root element describe the document.
+func xmlCommentAnchor(n *xmlNode, i int) (*xmlNode, commentAnchor) {
+	if n.children[i].trailing {
+		return n.children[i-1], anchorTrailing
+	}
+	for _, c := range n.children[i+1:] {
+		if c.kind != xmlElementNode {
+			continue
+		}
+		if n.kind == xmlDocumentNode {
+			return n, anchorFile
+		}
+		return c, anchorLeading
+	}
+	if n.kind == xmlDocumentNode {
+		return n, anchorFooter
+	}
+	return n, anchorClosing
+}
+
+// hasXMLComment reports whether the node has a comment with the anchor. For leading, closing and footer comments,
+// the comment must also have the same text.
+func hasXMLComment(target *xmlNode, anchor commentAnchor, text string) bool {
+	var candidates []*xmlNode
+	switch anchor {
+	case anchorTrailing:
+		return target.parent != nil && xmlTrailingEnd(target) > target.index()+1
+	case anchorLeading:
+		siblings := target.parent.children
+		for i := target.index() - 1; i >= 0 && siblings[i].kind == xmlCommentNode && !siblings[i].trailing; i-- {
+			candidates = append(candidates, siblings[i])
+		}
+	case anchorFile:
+		for _, c := range target.children {
+			if c.kind == xmlElementNode {
+				break
+			}
+			if c.kind == xmlCommentNode {
+				return true
+			}
+		}
+	case anchorClosing, anchorFooter:
+		for i := len(target.children) - 1; i >= 0 && target.children[i].kind != xmlElementNode; i-- {
+			candidates = append(candidates, target.children[i])
+		}
+	}
+	for _, c := range candidates {
+		if c.kind == xmlCommentNode && c.data == text {
+			return true
+		}
+	}
+	return false
+}
+
+// insertXMLComment inserts a comment with the text, attached to the node with the anchor.
+func insertXMLComment(target *xmlNode, anchor commentAnchor, text string) {
+	comment := &xmlNode{kind: xmlCommentNode, data: text}
+	switch anchor {
+	case anchorTrailing:
+		comment.trailing = true
+		target.parent.insertChild(xmlTrailingEnd(target), comment)
+	case anchorLeading:
+		target.parent.insertChild(target.index(), comment)
+	case anchorFile:
+		target.insertChild(0, comment)
+	default:
+		target.appendChild(comment)
+	}
+}
+
+// xmlTrailingEnd returns the index after the trailing comments of the element in its parent's children.
+func xmlTrailingEnd(elem *xmlNode) int {
+	siblings := elem.parent.children
+	i := elem.index() + 1
+	for i < len(siblings) && siblings[i].kind == xmlCommentNode && siblings[i].trailing {
+		i++
+	}
+	return i
+}
+
+// mergeXMLComments re-attaches the comments in the old XML document to the same paths in the new one. Comments that
+// already exist in the new document, e.g. because they were added by WithFieldComments or WithFileComment, take
+// precedence over the old ones. It returns the merged document and the comments whose paths no longer exist.
+func mergeXMLComments(oldData, newData []byte) ([]byte, []jsoncComment, error) {
+	oldDoc, err := parseXML(oldData)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing old document: %w", err)
+	}
+	newDoc, err := parseXML(newData)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing new document: %w", err)
+	}
+
+	oldPaths := xmlPaths(oldDoc)
+	newElems := make(map[string]*xmlNode)
+	for elem, path := range xmlPaths(newDoc) {
+		newElems[path] = elem
+	}
+
+	var lost []jsoncComment
+	var merged bool
+	var walk func(n *xmlNode)
+	walk = func(n *xmlNode) {
+		for i, c := range n.children {
+			if c.kind == xmlElementNode {
+				walk(c)
+			}
+			if c.kind != xmlCommentNode {
+				continue
+			}
+			target, anchor := xmlCommentAnchor(n, i)
+			path := oldPaths[target]
+			newTarget, ok := newElems[path]
+			if !ok {
+				lost = append(lost, jsoncComment{text: "<!--" + c.data + "-->", path: path, anchor: anchor})
+				continue
+			}
+			if hasXMLComment(newTarget, anchor, c.data) {
+				continue
+			}
+			insertXMLComment(newTarget, anchor, c.data)
+			merged = true
+		}
+	}
+	walk(oldDoc)
+	if !merged {
+		return newData, lost, nil
+	}
+	return renderXML(newDoc), lost, nil
+}
+
+// diffXML parses the golden file and the actual result as XML and returns their differences, with the XPath-like
+// paths of the elements and attributes. Comments, whitespace and the order of attributes are ignored. The returned bool
+// is false when either of them cannot be parsed.
+func diffXML(golden, actual []byte) ([]jsonDiff, bool) {
+	goldenDoc, err := parseXML(golden)
+	if err != nil {
+		return nil, false
+	}
+	actualDoc, err := parseXML(actual)
+	if err != nil {
+		return nil, false
+	}
+
+	want, got := goldenDoc.root(), actualDoc.root()
+	if want.name != got.name {
+		return []jsonDiff{{kind: diffChanged, path: "/", old: compactXML(want), new: compactXML(got)}}, true
+	}
+	var diffs []jsonDiff
+	walkXMLDiff("/"+want.name, want, got, &diffs)
+	return diffs, true
+}
+
+// walkXMLDiff appends the differences between the two elements at path to diffs. Child elements are matched by their
+// name and position among the siblings with the same name. If the children only differ in their order, e.g. when
+// siblings with different names are swapped, the whole element is reported as changed.
+func walkXMLDiff(path string, want, got *xmlNode, diffs *[]jsonDiff) {
+	wantAttrs := make(map[string]string)
+	for _, a := range want.attrs {
+		wantAttrs[a.name] = a.value
+	}
+	gotAttrs := make(map[string]string)
+	for _, a := range got.attrs {
+		gotAttrs[a.name] = a.value
+	}
+	var names []string
+	for _, a := range want.attrs {
+		names = append(names, a.name)
+	}
+	for _, a := range got.attrs {
+		if _, ok := wantAttrs[a.name]; !ok {
+			names = append(names, a.name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		attrPath := path + "/@" + name
+		wantValue, inWant := wantAttrs[name]
+		gotValue, inGot := gotAttrs[name]
+		switch {
+		case !inGot:
+			*diffs = append(*diffs, jsonDiff{kind: diffRemoved, path: attrPath, old: wantValue})
+		case !inWant:
+			*diffs = append(*diffs, jsonDiff{kind: diffAdded, path: attrPath, new: gotValue})
+		case wantValue != gotValue:
+			*diffs = append(*diffs, jsonDiff{kind: diffChanged, path: attrPath, old: wantValue, new: gotValue})
+		}
+	}
+
+	if wantText, gotText := want.text(), got.text(); wantText != gotText {
+		*diffs = append(*diffs, jsonDiff{kind: diffChanged, path: path, old: wantText, new: gotText})
+	}
+
+	if wantOrder, gotOrder := xmlChildOrder(want), xmlChildOrder(got); !slices.Equal(wantOrder, gotOrder) &&
+		slices.Equal(sortedCopy(wantOrder), sortedCopy(gotOrder)) {
+		*diffs = append(*diffs, jsonDiff{kind: diffChanged, path: path, old: compactXML(want), new: compactXML(got)})
+	}
+
+	// Child element names in the order they first appear, in the golden file and then in the actual result.
+	var childNames []string
+	seen := make(map[string]bool)
+	for _, n := range append(append([]*xmlNode{}, want.children...), got.children...) {
+		if n.kind == xmlElementNode && !seen[n.name] {
+			seen[n.name] = true
+			childNames = append(childNames, n.name)
+		}
+	}
+	for _, name := range childNames {
+		wantElems, gotElems := want.childElements(name), got.childElements(name)
+		count := max(len(wantElems), len(gotElems))
+		for i := 0; i < count; i++ {
+			elemPath := path + "/" + name
+			if count > 1 {
+				elemPath += "[" + strconv.Itoa(i+1) + "]"
+			}
+			switch {
+			case i >= len(gotElems):
+				*diffs = append(*diffs, jsonDiff{kind: diffRemoved, path: elemPath, old: compactXML(wantElems[i])})
+			case i >= len(wantElems):
+				*diffs = append(*diffs, jsonDiff{kind: diffAdded, path: elemPath, new: compactXML(gotElems[i])})
+			default:
+				walkXMLDiff(elemPath, wantElems[i], gotElems[i], diffs)
+			}
+		}
+	}
+}
+
+// sortedCopy returns a sorted copy of the strings.
+func sortedCopy(s []string) []string {
+	sorted := slices.Clone(s)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// xmlChildOrder returns the sequence of the element's children, ignoring comments. Elements are represented by their
+// names in angle brackets, and text by the text.
+func xmlChildOrder(n *xmlNode) []string {
+	var order []string
+	for _, c := range n.children {
+		switch c.kind {
+		case xmlElementNode:
+			order = append(order, "<"+c.name+">")
+		case xmlTextNode:
+			order = append(order, c.data)
+		}
+	}
+	return order
+}
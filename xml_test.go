@@ -0,0 +1,434 @@
+package golden
+
+import (
+	"encoding/xml"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// rssFeed is an RSS feed, with xml struct tags.
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	ID    string `xml:"id,attr"`
+	Lang  string `xml:"lang,attr"`
+	Title string `xml:"title"`
+	GUID  string `xml:"guid"`
+}
+
+func newRSSFeed() rssFeed {
+	return rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         "Release notes",
+			LastBuildDate: "Mon, 01 Jan 2024 00:00:00 GMT",
+			Items: []rssItem{
+				{ID: "1", Lang: "en", Title: "Golden files & you", GUID: "3f2b8c1e-6a4d-4c1b-9e2f-1a2b3c4d5e6f"},
+				{ID: "2", Lang: "sv", Title: "Gyllene filer", GUID: "9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d"},
+			},
+		},
+	}
+}
+
+func TestAssertXML(t *testing.T) {
+	type args struct {
+		t       *testing.T
+		want    string
+		got     any
+		options []Option
+	}
+	type given struct {
+		args args
+	}
+	type test struct {
+		name  string
+		given given
+	}
+	tests := []test{
+		{
+			name: "passes when the golden file equals got",
+			given: given{
+				args: args{
+					want: "testdata/assert_xml/feed.xml",
+					got:  newRSSFeed(),
+				},
+			},
+		},
+		{
+			name: "passes when raw XML only differs in attribute order and whitespace",
+			given: given{
+				args: args{
+					want: "testdata/assert_xml/feed.xml",
+					got: `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"><channel>
+  <title>Release notes</title>
+  <lastBuildDate>Mon, 01 Jan 2024 00:00:00 GMT</lastBuildDate>
+  <item lang="en" id="1"><title>Golden files &amp; you</title><guid>3f2b8c1e-6a4d-4c1b-9e2f-1a2b3c4d5e6f</guid></item>
+  <item lang="sv" id="2"><title>Gyllene filer</title><guid>9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d</guid></item>
+</channel></rss>`,
+				},
+			},
+		},
+		{
+			name: "passes with skipped nodes, field comments and a file comment",
+			given: given{
+				args: args{
+					want: "testdata/assert_xml/feed_with_options.xml",
+					got:  newRSSFeed(),
+					options: []Option{
+						WithSkippedFields("/rss/channel/lastBuildDate", "//item/guid", "/rss/channel/item[@lang='sv']/@id"),
+						WithFieldComments([]FieldComment{
+							{Path: "/rss/channel/item[2]/title", Comment: "Translated -- by hand"},
+						}),
+						WithFileComment("Rendered by the feed handler"),
+					},
+				},
+			},
+		},
+		{
+			name: "passes when skipping namespaced elements by their local names",
+			given: given{
+				args: args{
+					want: "testdata/assert_xml/soap.xml",
+					got: []byte(`<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope">` +
+						`<soap:Body><m:LoginResponse xmlns:m="urn:auth"><m:Token>eyJhbGciOi</m:Token></m:LoginResponse>` +
+						`</soap:Body></soap:Envelope>`),
+					options: []Option{WithSkippedFields("/Envelope/Body/*/Token")},
+				},
+			},
+		},
+		{
+			name: "passes when replacing elements and attributes with stable placeholders",
+			given: given{
+				args: args{
+					want: "testdata/assert_xml/order_placeholders.xml",
+					got: []byte(`<order><id>a91e</id><customer id="7f3c"/><lines>` +
+						`<line customer="7f3c"><sku>X-1</sku></line><line customer="b210"><sku>X-2</sku></line>` +
+						`</lines></order>`),
+					options: []Option{WithStablePlaceholders("/order/id", "/order/customer/@id", "//line/@customer")},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- Given --------------------------------- */
+			tt.given.args.t = &testing.T{} // test result recorder
+
+			/* ---------------------------------- When ---------------------------------- */
+			AssertXML(tt.given.args.t, tt.given.args.want, tt.given.args.got, tt.given.args.options...)
+
+			/* ---------------------------------- Then ---------------------------------- */
+			require.False(t, tt.given.args.t.Failed())
+		})
+	}
+}
+
+func TestAssertXML_Failure(t *testing.T) {
+	type args struct {
+		want    string
+		got     any
+		options []Option
+	}
+	type given struct {
+		args args
+	}
+	type want struct {
+		// failure is a substring of the failure report
+		failure string
+	}
+	type test struct {
+		name  string
+		given given
+		want  want
+	}
+	tests := []test{
+		{
+			name: "fails with the differences by path when the golden file is different from got",
+			given: given{
+				args: args{
+					want: "testdata/assert_xml/feed.xml",
+					got: func() rssFeed {
+						f := newRSSFeed()
+						f.Channel.Items[1].Title = "Golden files"
+						return f
+					}(),
+				},
+			},
+			want: want{failure: `changed      /rss/channel/item[2]/title: "Gyllene filer" => "Golden files"`},
+		},
+		{
+			name: "fails with the differences by path when an attribute is different",
+			given: given{
+				args: args{
+					want: "testdata/assert_xml/feed.xml",
+					got: func() rssFeed {
+						f := newRSSFeed()
+						f.Version = "0.91"
+						return f
+					}(),
+				},
+			},
+			want: want{failure: `changed      /rss/@version: "2.0" => "0.91"`},
+		},
+		{
+			name: "fails with the removed element when got has fewer elements",
+			given: given{
+				args: args{
+					want: "testdata/assert_xml/feed.xml",
+					got: func() rssFeed {
+						f := newRSSFeed()
+						f.Channel.Items = f.Channel.Items[:1]
+						return f
+					}(),
+				},
+			},
+			want: want{failure: `removed      /rss/channel/item[2]: "<item id=\"2\" lang=\"sv\"><title>Gyllene filer</title>`},
+		},
+		{
+			name: "fails with the changed element when siblings with different names are reordered",
+			given: given{
+				args: args{
+					want: "testdata/assert_xml/feed.xml",
+					got: func() []byte {
+						feed := string(readFile(t, "testdata/assert_xml/feed.xml"))
+						title := "<title>Release notes</title>"
+						date := "<lastBuildDate>Mon, 01 Jan 2024 00:00:00 GMT</lastBuildDate>"
+						return []byte(strings.NewReplacer(title, date, date, title).Replace(feed))
+					}(),
+				},
+			},
+			want: want{failure: `=> "<channel><lastBuildDate>Mon, 01 Jan 2024 00:00:00 GMT</lastBuildDate><title>Release notes</title>`},
+		},
+		{
+			name: "fails when skipping non-existent node",
+			given: given{
+				args: args{
+					want:    "testdata/assert_xml/feed.xml",
+					got:     newRSSFeed(),
+					options: []Option{WithSkippedFields("/rss/channel/author")},
+				},
+			},
+			want: want{failure: "path = /rss/channel/author: path not found"},
+		},
+		{
+			name: "fails when skipping with KeepType",
+			given: given{
+				args: args{
+					want:    "testdata/assert_xml/feed.xml",
+					got:     newRSSFeed(),
+					options: []Option{WithSkippedFields(KeepType("/rss/channel/title"))},
+				},
+			},
+			want: want{failure: "path = /rss/channel/title: KeepType is not supported for XML golden files"},
+		},
+		{
+			name: "fails when the path is invalid",
+			given: given{
+				args: args{
+					want:    "testdata/assert_xml/feed.xml",
+					got:     newRSSFeed(),
+					options: []Option{WithSkippedFields("rss.channel.title")},
+				},
+			},
+			want: want{failure: "path = rss.channel.title: invalid path: path must start with /"},
+		},
+		{
+			name: "fails when got is not valid XML",
+			given: given{
+				args: args{
+					want: "testdata/assert_xml/feed.xml",
+					got:  "<rss><channel></rss>",
+				},
+			},
+			want: want{failure: "parsing got"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- Given --------------------------------- */
+			tb := newFakeTB(t.Name()) // test result recorder
+
+			/* ---------------------------------- When ---------------------------------- */
+			AssertXML(tb, tt.given.args.want, tt.given.args.got, tt.given.args.options...)
+
+			/* ---------------------------------- Then ---------------------------------- */
+			require.True(t, tb.Failed())
+			require.NotEmpty(t, tb.errors)
+			require.Contains(t, tb.errors[0], tt.want.failure)
+		})
+	}
+}
+
+func TestAssertXML_UpdateFlag(t *testing.T) {
+	type args struct {
+		t       *testing.T
+		want    string
+		got     any
+		options []Option
+	}
+	type given struct {
+		args args
+	}
+	type want struct {
+		// xml is the expected XML content of the golden file
+		xml string
+	}
+	type test struct {
+		name  string
+		given given
+		want  want
+	}
+	tests := []test{
+		{
+			name: "overwrites the golden file",
+			given: given{
+				args: args{
+					want: "testdata/assert_xml_update_flag/overwrites.xml",
+					got:  `<user name="John"><age>30</age><tags/></user>`,
+				},
+			},
+			want: want{
+				xml: `<user name="John">
+    <age>30</age>
+    <tags/>
+</user>
+`,
+			},
+		},
+		{
+			name: "preserves hand-written comments",
+			given: given{
+				args: args{
+					want:    "testdata/assert_xml_update_flag/preserves_comments.xml",
+					got:     `<user><age>31</age><name>John</name></user>`,
+					options: []Option{WithFieldComments([]FieldComment{{Path: "/user/name", Comment: "Generated"}})},
+				},
+			},
+			want: want{
+				xml: `<!-- Reviewed by hand -->
+
+<user>
+    <!-- The age in years -->
+    <age>31</age>
+    <name>John</name> <!-- Generated -->
+</user>
+`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- Given --------------------------------- */
+			// Update a copy of the golden file, since a golden file is only written once per test run
+			initialGoldenFile := readFile(t, tt.given.args.want)
+			tt.given.args.want = filepath.Join(t.TempDir(), filepath.Base(tt.given.args.want))
+			writeFile(t, tt.given.args.want, initialGoldenFile)
+
+			tt.given.args.t = t
+
+			/* ---------------------------------- When ---------------------------------- */
+			AssertXML(tt.given.args.t, tt.given.args.want, tt.given.args.got, append(tt.given.args.options, UpdateGoldenFiles())...)
+
+			/* ---------------------------------- Then ---------------------------------- */
+			got := readFile(t, tt.given.args.want)
+			require.NotEqual(t, initialGoldenFile, got, "golden file should be updated")
+			require.Equal(t, tt.want.xml, string(got), "comparison with golden file failed")
+		})
+	}
+}
+
+func TestEvalXPath(t *testing.T) {
+	type given struct {
+		path string
+	}
+	type want struct {
+		// paths are the canonical paths of the matches
+		paths []string
+		err   string
+	}
+	type test struct {
+		name  string
+		given given
+		want  want
+	}
+	tests := []test{
+		{
+			name:  "matches child elements by name",
+			given: given{path: "/rss/channel/item/title"},
+			want:  want{paths: []string{"/rss/channel/item[1]/title", "/rss/channel/item[2]/title"}},
+		},
+		{
+			name:  "matches elements at any depth",
+			given: given{path: "//guid"},
+			want:  want{paths: []string{"/rss/channel/item[1]/guid", "/rss/channel/item[2]/guid"}},
+		},
+		{
+			name:  "matches elements by position",
+			given: given{path: "/rss/*/item[2]"},
+			want:  want{paths: []string{"/rss/channel/item[2]"}},
+		},
+		{
+			name:  "matches elements by attribute value",
+			given: given{path: `/rss/channel/item[@lang="en"]/title`},
+			want:  want{paths: []string{"/rss/channel/item[1]/title"}},
+		},
+		{
+			name:  "matches attributes",
+			given: given{path: "//item/@id"},
+			want:  want{paths: []string{"/rss/channel/item[1]/@id", "/rss/channel/item[2]/@id"}},
+		},
+		{
+			name:  "returns an error for an unsupported predicate",
+			given: given{path: "/rss/channel/item[last()]"},
+			want:  want{err: "unsupported predicate [last()], use a position or [@name='value']"},
+		},
+		{
+			name:  "returns an error for a step after an attribute",
+			given: given{path: "/rss/@version/channel"},
+			want:  want{err: "an attribute step must be the last step, without predicates"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- Given --------------------------------- */
+			data, err := xml.Marshal(newRSSFeed())
+			require.NoError(t, err)
+			doc, err := parseXML(data)
+			require.NoError(t, err)
+			paths := xmlPaths(doc)
+
+			/* ---------------------------------- When ---------------------------------- */
+			matches, err := evalXPath(doc, tt.given.path)
+
+			/* ---------------------------------- Then ---------------------------------- */
+			if tt.want.err != "" {
+				require.EqualError(t, err, tt.want.err)
+				return
+			}
+			require.NoError(t, err)
+			var got []string
+			for _, m := range matches {
+				path := paths[m.elem]
+				if m.attr >= 0 {
+					path += "/@" + m.elem.attrs[m.attr].name
+				}
+				got = append(got, path)
+			}
+			require.Equal(t, tt.want.paths, got)
+		})
+	}
+}
@@ -0,0 +1,374 @@
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AssertYAML compares the YAML representation of got with the YAML golden file at want. If they are not equal, the test
+// is marked as failed, but execution continues.
+//
+// got is marshalled to JSON first, using its json struct tags and MarshalJSON methods, or protojson for protobuf
+// messages, and the JSON document is then converted to YAML. This is how Kubernetes API types are converted to YAML,
+// so manifests render as expected. All options work on the JSON document, so their GJSON paths are the same as for
+// AssertJSON. Field comments and the file comment are rendered as "# comment".
+//
+// To update the golden file with the actual value instead of comparing with it, set the environment variable
+// "UPDATE_GOLDENS" to "1" when running the tests.
+//
+// Example: AssertYAML(t, "testdata/deployment.yaml", deployment, WithSkippedFields("metadata.creationTimestamp"))
+func AssertYAML(t testing.TB, want string, got any, opts ...Option) {
+	t.Helper()
+	compareYAML(t, false, want, got, append(opts, envOptions(t, want)...)...)
+}
+
+// RequireYAML is like AssertYAML, but if the golden file and got are not equal, the test is marked as failed and
+// execution stops.
+func RequireYAML(t testing.TB, want string, got any, opts ...Option) {
+	t.Helper()
+	compareYAML(t, true, want, got, append(opts, envOptions(t, want)...)...)
+}
+
+func compareYAML(t testing.TB, failNow bool, want string, got any, opts ...Option) {
+	t.Helper()
+
+	gotBytes, err := marshalGot(got, opts)
+	if !NoError(t, failNow, err, "marshalling got") {
+		return
+	}
+
+	compareGolden(t, failNow, want, &Document{result: gotBytes}, yamlFormat, opts...)
+}
+
+// yamlFormat is the format of YAML golden files.
+var yamlFormat = goldenFormat{
+	render:        func(doc *Document) ([]byte, error) { return renderYAML(doc.result) },
+	mergeComments: mergeYAMLComments,
+	diff: func(golden, actual []byte) ([]jsonDiff, bool) {
+		goldenJSON, err := yamlToJSON(golden)
+		if err != nil {
+			return nil, false
+		}
+		actualJSON, err := yamlToJSON(actual)
+		if err != nil {
+			return nil, false
+		}
+		return diffGolden(goldenJSON, actualJSON)
+	},
+}
+
+// renderYAML converts a JSONC document to YAML, keeping the order of object keys. The comments are converted to YAML
+// comments attached to the same values.
+func renderYAML(data []byte) ([]byte, error) {
+	jdoc, err := scanJSONC(data)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(stripJSONComments(data)))
+	dec.UseNumber()
+	b := &yamlBuilder{dec: dec, owners: make(map[string]*yaml.Node), values: make(map[string]*yaml.Node)}
+	root, err := b.build("")
+	if err != nil {
+		return nil, err
+	}
+	doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}
+
+	for _, c := range jdoc.comments {
+		text := yamlComment(c.text)
+		value, ok := b.values[c.path]
+		if !ok {
+			continue
+		}
+		owner := b.owners[c.path] // nil for array elements and the top-level value
+
+		switch c.anchor {
+		case anchorFile:
+			appendYAMLComment(&doc.HeadComment, text)
+		case anchorFooter:
+			appendYAMLComment(&doc.FootComment, text)
+		case anchorLeading:
+			if owner == nil {
+				owner = value
+			}
+			appendYAMLComment(&owner.HeadComment, text)
+		case anchorTrailing, anchorOpening:
+			// The value of an object or array starts on the line after its key, so the comment goes on the key's line.
+			if owner == nil || value.Kind == yaml.ScalarNode {
+				owner = value
+			}
+			appendYAMLComment(&owner.LineComment, text)
+		case anchorClosing:
+			if len(value.Content) == 0 {
+				appendYAMLComment(&value.FootComment, text)
+				continue
+			}
+			appendYAMLComment(&value.Content[len(value.Content)-1].FootComment, text)
+		}
+	}
+	return encodeYAML(doc)
+}
+
+// yamlBuilder builds a YAML node tree from a JSON token stream, indexing the nodes by their GJSON paths.
+type yamlBuilder struct {
+	dec *json.Decoder
+	// owners are the key nodes of object fields.
+	owners map[string]*yaml.Node
+	// values are the value nodes of all values.
+	values map[string]*yaml.Node
+}
+
+// build builds the node for the next value in the token stream, which is at path.
+func (b *yamlBuilder) build(path string) (*yaml.Node, error) {
+	tok, err := b.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	var n *yaml.Node
+	switch v := tok.(type) {
+	case json.Delim:
+		switch v {
+		case '{':
+			n = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			for b.dec.More() {
+				keyTok, err := b.dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: keyTok.(string)}
+				fieldPath := joinPath(path, escapePathKey(key.Value))
+				b.owners[fieldPath] = key
+				value, err := b.build(fieldPath)
+				if err != nil {
+					return nil, err
+				}
+				n.Content = append(n.Content, key, value)
+			}
+		case '[':
+			n = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			for i := 0; b.dec.More(); i++ {
+				value, err := b.build(joinPath(path, strconv.Itoa(i)))
+				if err != nil {
+					return nil, err
+				}
+				n.Content = append(n.Content, value)
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q", v)
+		}
+		// Consume the closing bracket
+		if _, err := b.dec.Token(); err != nil {
+			return nil, err
+		}
+	case string:
+		n = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+	case json.Number:
+		// Numbers, booleans and null are left untagged, so that they are written as is instead of with explicit tags.
+		n = &yaml.Node{Kind: yaml.ScalarNode, Value: v.String()}
+	case bool:
+		n = &yaml.Node{Kind: yaml.ScalarNode, Value: strconv.FormatBool(v)}
+	case nil:
+		n = &yaml.Node{Kind: yaml.ScalarNode, Value: "null"}
+	}
+	b.values[path] = n
+	return n, nil
+}
+
+// yamlComment converts a JSONC comment, including its comment markers, to a YAML comment.
+func yamlComment(text string) string {
+	switch {
+	case strings.HasPrefix(text, "//"):
+		text = text[2:]
+	case strings.HasPrefix(text, "/*"):
+		text = strings.TrimSuffix(text[2:], "*/")
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		lines = append(lines, strings.TrimRight("# "+strings.TrimSpace(line), " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// appendYAMLComment appends a comment to the comment field, on a new line.
+func appendYAMLComment(field *string, text string) {
+	if *field != "" {
+		*field += "\n"
+	}
+	*field += text
+}
+
+// encodeYAML encodes the YAML node with an indentation of two spaces.
+func encodeYAML(n *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(n); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// mergeYAMLComments re-attaches the comments in the old YAML document to the same GJSON paths in the new one.
+// Comments that already exist in the new document, e.g. because they were added by WithFieldComments or
+// WithFileComment, take precedence over the old ones. It returns the merged document and the comments whose paths no
+// longer exist. Like for mergeComments, comments that WithFieldComments added to the old document are kept as well.
+func mergeYAMLComments(oldData, newData []byte) ([]byte, []jsoncComment, error) {
+	var oldDoc, newDoc yaml.Node
+	if err := yaml.Unmarshal(oldData, &oldDoc); err != nil {
+		return nil, nil, fmt.Errorf("parsing old document: %w", err)
+	}
+	if err := yaml.Unmarshal(newData, &newDoc); err != nil {
+		return nil, nil, fmt.Errorf("parsing new document: %w", err)
+	}
+
+	newComments := make(map[string][]*string)
+	walkYAML(&newDoc, func(path string, owner, value *yaml.Node) {
+		newComments[path] = yamlCommentFields(owner, value)
+	})
+
+	var lost []jsoncComment
+	var merged bool
+	walkYAML(&oldDoc, func(path string, owner, value *yaml.Node) {
+		fields, ok := newComments[path]
+		for i, text := range yamlCommentFields(owner, value) {
+			switch {
+			case *text == "":
+			case !ok:
+				lost = append(lost, jsoncComment{text: *text, path: path})
+			case *fields[i] == "":
+				*fields[i] = *text
+				merged = true
+			}
+		}
+	})
+	if !merged {
+		return newData, lost, nil
+	}
+	out, err := encodeYAML(&newDoc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, lost, nil
+}
+
+// walkYAML calls fn for every value in the YAML document, with its GJSON path. The owner is the key node of an object
+// field, the document node for the top-level value, and nil for array elements.
+func walkYAML(doc *yaml.Node, fn func(path string, owner, value *yaml.Node)) {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return
+	}
+	var walk func(path string, owner, value *yaml.Node)
+	walk = func(path string, owner, value *yaml.Node) {
+		fn(path, owner, value)
+		switch value.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(value.Content); i += 2 {
+				key := value.Content[i]
+				walk(joinPath(path, escapePathKey(key.Value)), key, value.Content[i+1])
+			}
+		case yaml.SequenceNode:
+			for i, elem := range value.Content {
+				walk(joinPath(path, strconv.Itoa(i)), nil, elem)
+			}
+		}
+	}
+	walk("", doc, doc.Content[0])
+}
+
+// yamlCommentFields returns the comment fields of the owner and the value, in a fixed order.
+func yamlCommentFields(owner, value *yaml.Node) []*string {
+	if owner == nil {
+		owner = &yaml.Node{}
+	}
+	return []*string{
+		&owner.HeadComment, &owner.LineComment, &owner.FootComment,
+		&value.HeadComment, &value.LineComment, &value.FootComment,
+	}
+}
+
+// yamlToJSON converts a YAML document to JSON, keeping the order of mapping keys.
+func yamlToJSON(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, errors.New("document has no value")
+	}
+	var buf bytes.Buffer
+	if err := writeYAMLAsJSON(&buf, doc.Content[0]); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeYAMLAsJSON writes the YAML node as JSON. Scalars that have no JSON equivalent, e.g. timestamps and infinite
+// numbers, are written as strings.
+func writeYAMLAsJSON(buf *bytes.Buffer, n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.AliasNode:
+		return writeYAMLAsJSON(buf, n.Alias)
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, err := json.Marshal(n.Content[i].Value)
+			if err != nil {
+				return err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := writeYAMLAsJSON(buf, n.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, elem := range n.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeYAMLAsJSON(buf, elem); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case yaml.ScalarNode:
+		var v any
+		if err := n.Decode(&v); err != nil {
+			return err
+		}
+		switch f := v.(type) {
+		case float64:
+			if math.IsInf(f, 0) || math.IsNaN(f) {
+				v = n.Value
+			}
+		case time.Time, []byte:
+			v = n.Value
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+	default:
+		return fmt.Errorf("unsupported YAML node kind %d", n.Kind)
+	}
+	return nil
+}
@@ -0,0 +1,313 @@
+package golden
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// deployment is a Kubernetes-like manifest, with json struct tags only.
+type deployment struct {
+	APIVersion string             `json:"apiVersion"`
+	Kind       string             `json:"kind"`
+	Metadata   deploymentMetadata `json:"metadata"`
+	Spec       deploymentSpec     `json:"spec"`
+}
+
+type deploymentMetadata struct {
+	Name              string            `json:"name"`
+	Labels            map[string]string `json:"labels"`
+	CreationTimestamp string            `json:"creationTimestamp"`
+}
+
+type deploymentSpec struct {
+	Replicas   int                   `json:"replicas"`
+	Containers []deploymentContainer `json:"containers"`
+	Paused     bool                  `json:"paused"`
+	Strategy   *string               `json:"strategy"`
+}
+
+type deploymentContainer struct {
+	Name  string   `json:"name"`
+	Image string   `json:"image"`
+	Args  []string `json:"args"`
+}
+
+func newDeployment() deployment {
+	return deployment{
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+		Metadata: deploymentMetadata{
+			Name:              "web",
+			Labels:            map[string]string{"app": "web", "version": "1.0"},
+			CreationTimestamp: "2024-01-01T00:00:00Z",
+		},
+		Spec: deploymentSpec{
+			Replicas: 3,
+			Containers: []deploymentContainer{
+				{Name: "web", Image: "nginx:1.25", Args: []string{"--port", "8080"}},
+			},
+			Paused: false,
+		},
+	}
+}
+
+func TestAssertYAML(t *testing.T) {
+	type args struct {
+		t       *testing.T
+		want    string
+		got     any
+		options []Option
+	}
+	type given struct {
+		args args
+	}
+	type test struct {
+		name  string
+		given given
+	}
+	tests := []test{
+		{
+			name: "passes when the golden file equals got",
+			given: given{
+				args: args{
+					want: "testdata/assert_yaml/deployment.yaml",
+					got:  newDeployment(),
+				},
+			},
+		},
+		{
+			name: "passes with skipped fields, field comments and a file comment",
+			given: given{
+				args: args{
+					want: "testdata/assert_yaml/deployment_with_options.yaml",
+					got:  newDeployment(),
+					options: []Option{
+						WithSkippedFields("metadata.creationTimestamp"),
+						WithFieldComments([]FieldComment{
+							{Path: "spec.replicas", Comment: "Scaled by the autoscaler"},
+							{Path: "spec.containers.0.image", Comment: "Pinned version"},
+						}),
+						WithFileComment("Rendered by the web chart"),
+					},
+				},
+			},
+		},
+		{
+			name: "passes when comparing semantically with a hand-formatted golden file",
+			given: given{
+				args: args{
+					want:    "testdata/assert_yaml/semantic_compare_hand_formatted.yaml",
+					got:     map[string]any{"name": "John", "age": 30, "tags": []string{"a", "b"}},
+					options: []Option{WithSemanticCompare()},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- Given --------------------------------- */
+			tt.given.args.t = &testing.T{} // test result recorder
+
+			/* ---------------------------------- When ---------------------------------- */
+			AssertYAML(tt.given.args.t, tt.given.args.want, tt.given.args.got, tt.given.args.options...)
+
+			/* ---------------------------------- Then ---------------------------------- */
+			require.False(t, tt.given.args.t.Failed())
+		})
+	}
+}
+
+func TestAssertYAML_Failure(t *testing.T) {
+	type args struct {
+		want    string
+		got     any
+		options []Option
+	}
+	type given struct {
+		args args
+	}
+	type want struct {
+		// failure is a substring of the failure report
+		failure string
+	}
+	type test struct {
+		name  string
+		given given
+		want  want
+	}
+	tests := []test{
+		{
+			name: "fails with the differences by path when the golden file is different from got",
+			given: given{
+				args: args{
+					want: "testdata/assert_yaml/deployment.yaml",
+					got: func() deployment {
+						d := newDeployment()
+						d.Spec.Replicas = 5
+						return d
+					}(),
+				},
+			},
+			want: want{failure: "changed      spec.replicas: 3 => 5"},
+		},
+		{
+			name: "fails when skipping non-existent field",
+			given: given{
+				args: args{
+					want:    "testdata/assert_yaml/deployment.yaml",
+					got:     newDeployment(),
+					options: []Option{WithSkippedFields("metadata.namespace")},
+				},
+			},
+			want: want{failure: "path = metadata.namespace: path not found"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- Given --------------------------------- */
+			tb := newFakeTB(t.Name()) // test result recorder
+
+			/* ---------------------------------- When ---------------------------------- */
+			AssertYAML(tb, tt.given.args.want, tt.given.args.got, tt.given.args.options...)
+
+			/* ---------------------------------- Then ---------------------------------- */
+			require.True(t, tb.Failed())
+			require.NotEmpty(t, tb.errors)
+			require.Contains(t, tb.errors[0], tt.want.failure)
+		})
+	}
+}
+
+func TestAssertYAML_UpdateFlag(t *testing.T) {
+	type args struct {
+		t       *testing.T
+		want    string
+		got     any
+		options []Option
+	}
+	type given struct {
+		args args
+	}
+	type want struct {
+		// yaml is the expected YAML content of the golden file
+		yaml string
+	}
+	type test struct {
+		name  string
+		given given
+		want  want
+	}
+	tests := []test{
+		{
+			name: "overwrites the golden file",
+			given: given{
+				args: args{
+					want: "testdata/assert_yaml_update_flag/overwrites.yaml",
+					got:  map[string]any{"name": "John", "age": 30, "tags": []string{}},
+				},
+			},
+			want: want{
+				yaml: `age: 30
+name: John
+tags: []
+`,
+			},
+		},
+		{
+			name: "preserves hand-written comments",
+			given: given{
+				args: args{
+					want:    "testdata/assert_yaml_update_flag/preserves_comments.yaml",
+					got:     map[string]any{"name": "John", "age": 30},
+					options: []Option{WithFieldComments([]FieldComment{{Path: "name", Comment: "Generated"}})},
+				},
+			},
+			want: want{
+				yaml: `# Reviewed by hand
+
+age: 30 # Should be the age in years
+name: John # Generated
+`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- Given --------------------------------- */
+			// Update a copy of the golden file, since a golden file is only written once per test run
+			initialGoldenFile := readFile(t, tt.given.args.want)
+			tt.given.args.want = filepath.Join(t.TempDir(), filepath.Base(tt.given.args.want))
+			writeFile(t, tt.given.args.want, initialGoldenFile)
+
+			tt.given.args.t = t
+
+			/* ---------------------------------- When ---------------------------------- */
+			AssertYAML(tt.given.args.t, tt.given.args.want, tt.given.args.got, append(tt.given.args.options, UpdateGoldenFiles())...)
+
+			/* ---------------------------------- Then ---------------------------------- */
+			got := readFile(t, tt.given.args.want)
+			require.NotEqual(t, initialGoldenFile, got, "golden file should be updated")
+			require.Equal(t, tt.want.yaml, string(got), "comparison with golden file failed")
+		})
+	}
+}
+
+func TestAssertYAML_HandWrittenComments(t *testing.T) {
+	/* ---------------------------------- Given --------------------------------- */
+	want := filepath.Join(t.TempDir(), "preserves_comments.yaml")
+	writeFile(t, want, readFile(t, "testdata/assert_yaml_update_flag/preserves_comments.yaml"))
+	got := map[string]any{"name": "John", "age": 30}
+	updateTB := newFakeTB(t.Name()) // test result recorder
+	AssertYAML(updateTB, want, got, UpdateGoldenFiles())
+	requireFailures(t, updateTB)
+	tb := newFakeTB(t.Name()) // test result recorder
+
+	/* ---------------------------------- When ---------------------------------- */
+	AssertYAML(tb, want, got)
+
+	/* ---------------------------------- Then ---------------------------------- */
+	requireFailures(t, tb)
+}
+
+func TestYAMLToJSON(t *testing.T) {
+	type given struct {
+		yaml string
+	}
+	type want struct {
+		json string
+	}
+	type test struct {
+		name  string
+		given given
+		want  want
+	}
+	tests := []test{
+		{
+			name:  "keeps the order of mapping keys",
+			given: given{yaml: "b: 1\na: [x, true, null]\n"},
+			want:  want{json: `{"b":1,"a":["x",true,null]}`},
+		},
+		{
+			name:  "converts scalars without a JSON equivalent to strings",
+			given: given{yaml: "at: 2024-01-01T00:00:00Z\nlimit: .inf\n"},
+			want:  want{json: `{"at":"2024-01-01T00:00:00Z","limit":".inf"}`},
+		},
+		{
+			name:  "resolves aliases",
+			given: given{yaml: "base: &base {name: web}\ncopy: *base\n"},
+			want:  want{json: `{"base":{"name":"web"},"copy":{"name":"web"}}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			/* ---------------------------------- When ---------------------------------- */
+			got, err := yamlToJSON([]byte(tt.given.yaml))
+
+			/* ---------------------------------- Then ---------------------------------- */
+			require.NoError(t, err)
+			require.Equal(t, tt.want.json, string(got))
+		})
+	}
+}